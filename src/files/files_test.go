@@ -236,6 +236,36 @@ func Test_Write_File_BufIO(t *testing.T) {
 	assert.Equal(t, 10, count)
 }
 
+// Buffering pays off when writing many small pieces, as each `Write()`
+// on a file handle is a system call, whereas `bufio.Writer` only makes
+// one when its buffer is full. The below benchmarks write a line at a
+// time directly and with buffering, respectively:
+func Benchmark_Write_File_Direct(b *testing.B) {
+	fileHandle, err := os.Create(tmpRoot + "direct.txt")
+	if err != nil {
+		b.Fatal("Can't open file: ", err)
+	}
+	defer fileHandle.Close()
+
+	for i := 0; i < b.N; i++ {
+		fileHandle.WriteString("Hello 😀\n")
+	}
+}
+
+func Benchmark_Write_File_BufIO(b *testing.B) {
+	fileHandle, err := os.Create(tmpRoot + "buffered.txt")
+	if err != nil {
+		b.Fatal("Can't open file: ", err)
+	}
+	defer fileHandle.Close()
+
+	writer := bufio.NewWriter(fileHandle)
+	for i := 0; i < b.N; i++ {
+		writer.WriteString("Hello 😀\n")
+	}
+	writer.Flush()
+}
+
 // ## Reading from a File with Buffering
 // The `Read()` and `ReadString()` functions, for bytes and strings,
 // respectively, from the `bufio` package, allow to read files with
@@ -57,6 +57,7 @@ func Test_Channel(t *testing.T) {
 // The following annotated code uses the `execOrder` string to
 // demonstrate the order of execution using synchronous channels so
 // that their "blocking" behaviour can be appreciated.
+//go2md:diagram
 func Test_Channel2(t *testing.T) {
 	// Define a channel c
 	c := make(chan string)
@@ -106,6 +107,34 @@ func Test_Channel3(t *testing.T) {
 
 }
 
+// Buffering also saves the sender and the receiver from waiting for each
+// other on every message, as shown by the below benchmarks, which pass
+// messages to a receiving goroutine through an unbuffered and a buffered
+// channel, respectively:
+func Benchmark_Unbuffered_Channel(b *testing.B) {
+	c := make(chan int)
+	go func() {
+		for range c {
+		}
+	}()
+	for i := 0; i < b.N; i++ {
+		c <- i
+	}
+	close(c)
+}
+
+func Benchmark_Buffered_Channel(b *testing.B) {
+	c := make(chan int, 100)
+	go func() {
+		for range c {
+		}
+	}()
+	for i := 0; i < b.N; i++ {
+		c <- i
+	}
+	close(c)
+}
+
 // ## Channel Directions
 // Functions can specify whether channels provided as arguments can
 // only send, receive, (or both send a receive).
@@ -119,7 +148,7 @@ func Test_Channel5(t *testing.T) {
 		// msg := <-c // Won't compile
 	}
 	canOnlyReceive := func(c <-chan string) string {
-		// c <- msg // Won't compile
+		// c <- "message" // Won't compile
 		return (<-c)
 	}
 	canBothSendAndReceive := func(c chan string, msg string) string {
@@ -322,6 +351,7 @@ func Test_Channel25(t *testing.T) {
 // A timer may be aborted by invoking `timer.Stop()`, preventing the
 //  waiting goroutine from executing
 // the code after the channel _receive_ statement.
+//go2md:diagram
 func Test_Channel30(t *testing.T) {
 	execOrder := "A"
 
@@ -473,6 +503,31 @@ func Test_Channel55(t *testing.T) {
 	assert.Equal(t, int32(0), x) // May be inconsistent if goroutines are still active
 }
 
+// Atomic counters are also cheaper than mutexes, as shown by the below
+// benchmarks, which increment a counter from concurrent goroutines using
+// `sync.Mutex` as in `Test_Channel60` and `atomic.AddInt32()` as in
+// `Test_Channel55`, respectively:
+func Benchmark_Mutex_Counter(b *testing.B) {
+	var mutex sync.Mutex
+	var x = 0
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			mutex.Lock()
+			x = x + 1
+			mutex.Unlock()
+		}
+	})
+}
+
+func Benchmark_Atomic_Counter(b *testing.B) {
+	var x int32 = 0
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			atomic.AddInt32(&x, 1)
+		}
+	})
+}
+
 // ## Wait Groups
 // Wait Groups allow to treat a swarm of concurrent goroutines as one
 // logical unit of execution to spare the programmer from coordinating
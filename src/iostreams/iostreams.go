@@ -15,6 +15,8 @@ import (
 // converts said data to upper case, and then writes the results
 // to `Stdout`. It then uses the `Stderr` stream to report the
 // number of bytes read.
+//
+//go2md:run stdin="hello"
 func main() {
 	// Read all Stdin
 	data, _ := ioutil.ReadAll(os.Stdin)
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// The AsciiDoc output targets Asciidoctor (and so Antora). Headings carry
+// the pandoc identifiers as explicit anchors so that links between
+// chapters become cross references, and compiler messages are shown as
+// warning admonitions.
+
+// renderAsciidoc renders a document as AsciiDoc.
+func renderAsciidoc(doc *document) string {
+	adoc := ""
+	ids := headingIDs(doc.Blocks, map[string]int{})
+	for i, b := range doc.Blocks {
+		switch b.Kind {
+		case headingBlock:
+			adoc = adoc + fmt.Sprintf("\n[[%s]]\n%s %s\n", ids[i], strings.Repeat("=", b.Level+1), asciidocInline(b.Lines[0]))
+		case proseBlock:
+			adoc = adoc + asciidocProse(b.Lines)
+		case codeBlock:
+			adoc = adoc + "\n"
+			if b.Label != "" {
+				adoc = adoc + "[[" + b.Label + "]]\n." + asciidocEscape(b.Label) + "\n"
+			}
+			lines := b.Lines
+			if i+1 < len(doc.Blocks) && doc.Blocks[i+1].Kind == calloutBlock {
+				lines = asciidocCallouts(lines)
+			}
+			adoc = adoc + "[source,go]\n" + asciidocListing("----", lines)
+		case outputBlock:
+			if b.Label == "" {
+				adoc = adoc + "\n[WARNING]\n====\n" + asciidocListing("....", b.Lines) + "====\n"
+				continue
+			}
+			adoc = adoc + "\n." + asciidocEscape(b.Label) + "\n" + asciidocListing("....", b.Lines)
+		case badgeBlock:
+			adoc = adoc + "\n[.go-version]\n" + asciidocEscape(b.Lines[0]) + "\n"
+		case admonitionBlock:
+			adoc = adoc + "\n[" + strings.ToUpper(b.Label) + "]\n====" + asciidocProse(b.Lines) + "====\n"
+		case calloutBlock:
+			for _, line := range b.Lines {
+				marker, text, _ := strings.Cut(line, " ")
+				n, _ := calloutNumber(marker)
+				adoc = adoc + fmt.Sprintf("<%d> %s\n", n, asciidocEscape(text))
+			}
+		case diagramBlock:
+			adoc = adoc + "\n[mermaid]\n" + asciidocListing("....", b.Lines)
+		case benchmarkBlock:
+			adoc = adoc + "\n." + asciidocEscape(b.Label) + "\n[cols=\"<" + strings.Repeat(",>", strings.Count(b.Lines[0], "\t")) + "\",options=\"header\"]\n|===\n"
+			for _, line := range b.Lines {
+				adoc = adoc + "|" + strings.ReplaceAll(asciidocEscape(line), "\t", " |") + "\n"
+			}
+			adoc = adoc + "|===\n"
+		case sourceBlock:
+			_, justFile := filepath.Split(b.File)
+			adoc = adoc + fmt.Sprintf("\n[.source]\nSource: link:%s%s#L%d[%s]\n", srcRoot, b.File, b.Line, asciidocEscape(justFile))
+		}
+	}
+	return adoc
+}
+
+// asciidocCallouts returns lines with their callout markers turned into
+// the callouts of Asciidoctor, as in `pending++ // <1>`.
+func asciidocCallouts(lines []string) []string {
+	rewritten := []string{}
+	for _, line := range lines {
+		if comment := strings.LastIndex(line, " // "); comment >= 0 {
+			if n, ok := calloutNumber(line[comment+4:]); ok && calloutMarker(n) == line[comment+4:] {
+				line = fmt.Sprintf("%s // <%d>", line[:comment], n)
+			}
+		}
+		rewritten = append(rewritten, line)
+	}
+	return rewritten
+}
+
+// asciidocListing returns lines delimited by delimiter, without the
+// trailing blank ones.
+func asciidocListing(delimiter string, lines []string) string {
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return delimiter + "\n" + strings.Join(lines, "\n") + "\n" + delimiter + "\n"
+}
+
+func asciidocProse(lines []string) string {
+	adoc := ""
+	parts := splitProse(lines)
+	for i, part := range parts {
+		switch part.Kind {
+		case paragraphPart:
+			adoc = adoc + "\n"
+			for _, line := range part.Lines {
+				adoc = adoc + asciidocInline(line) + "\n"
+			}
+		case itemPart:
+			if i == 0 || parts[i-1].Kind != itemPart {
+				adoc = adoc + "\n"
+			}
+			adoc = adoc + "* " + asciidocInline(strings.Join(part.Lines, " ")) + "\n"
+		case fencePart:
+			adoc = adoc + "\n" + asciidocListing("....", part.Lines)
+		}
+	}
+	return adoc
+}
+
+var asciidocInlineRenderer = inlineRenderer{
+	Code:   func(code string) string { return "`+" + code + "+`" },
+	Emph:   func(text string) string { return "__" + text + "__" },
+	Strong: func(text string) string { return "**" + text + "**" },
+	Link: func(label string, target string) string {
+		if strings.HasPrefix(target, "#") {
+			return "<<" + target[1:] + "," + label + ">>"
+		}
+		return "link:" + target + "[" + label + "]"
+	},
+	Text: asciidocEscape,
+}
+
+func asciidocInline(text string) string {
+	return renderInline(text, asciidocInlineRenderer)
+}
+
+// asciidocEscape keeps text from being taken as markup, using the
+// built-in character attributes where there are some and unconstrained
+// passthroughs elsewhere.
+func asciidocEscape(text string) string {
+	return strings.NewReplacer(
+		"*", "{asterisk}",
+		"`", "{backtick}",
+		"^", "{caret}",
+		"~", "{tilde}",
+		"+", "{plus}",
+		"[", "{startsb}",
+		"]", "{endsb}",
+		"<", "{lt}",
+		">", "{gt}",
+		"_", "++_++",
+		"#", "++#++",
+	).Replace(text)
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// With -bench, the Benchmark_ functions of a chapter file are run at
+// conversion time with -benchmem, and each section holding some ends with
+// a table of their results: the time and the memory allocated per
+// operation, stamped with the platform and the Go release they ran on, so
+// that sections comparing approaches back their claims with figures.
+
+var benchmarkMode = false
+
+const benchmarkTimeout = 10 * time.Minute
+
+// benchmarkColumns are the header of a table of results.
+var benchmarkColumns = []string{"Benchmark", "ns/op", "B/op", "allocs/op"}
+
+// A benchmarkResult is a line of `go test -bench` output, with the
+// GOMAXPROCS suffix cut from the name. Sub-benchmarks keep their full
+// name, such as Benchmark_Write/buffered.
+type benchmarkResult struct {
+	Name        string
+	NsPerOp     string
+	BytesPerOp  string
+	AllocsPerOp string
+}
+
+// benchmarkFuncs returns the Benchmark_ functions of fileName by line.
+func benchmarkFuncs(fileName string) (map[int]string, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, fileName, nil, parser.SkipObjectResolution)
+	if err != nil {
+		return nil, err
+	}
+	found := map[int]string{}
+	for _, decl := range file.Decls {
+		if fn, ok := decl.(*ast.FuncDecl); ok && fn.Recv == nil && strings.HasPrefix(fn.Name.Name, "Benchmark_") {
+			found[fset.Position(fn.Pos()).Line] = fn.Name.Name
+		}
+	}
+	return found, nil
+}
+
+// parseBenchmarks returns the results in the output of `go test -bench`
+// and the platform they ran on, as GOOS/GOARCH.
+func parseBenchmarks(output string) ([]benchmarkResult, string) {
+	results := []benchmarkResult{}
+	goos, goarch := "", ""
+	for _, line := range strings.Split(output, "\n") {
+		if value, found := strings.CutPrefix(line, "goos: "); found {
+			goos = strings.TrimSpace(value)
+			continue
+		}
+		if value, found := strings.CutPrefix(line, "goarch: "); found {
+			goarch = strings.TrimSpace(value)
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 4 || !strings.HasPrefix(fields[0], "Benchmark") {
+			continue
+		}
+		result := benchmarkResult{Name: fields[0]}
+		if dash := strings.LastIndex(result.Name, "-"); dash > 0 {
+			if _, err := strconv.Atoi(result.Name[dash+1:]); err == nil {
+				result.Name = result.Name[:dash]
+			}
+		}
+		// Fields after the iteration count come in value and unit pairs
+		for i := 2; i+1 < len(fields); i += 2 {
+			switch fields[i+1] {
+			case "ns/op":
+				result.NsPerOp = fields[i]
+			case "B/op":
+				result.BytesPerOp = fields[i]
+			case "allocs/op":
+				result.AllocsPerOp = fields[i]
+			}
+		}
+		if result.NsPerOp != "" {
+			results = append(results, result)
+		}
+	}
+	return results, goos + "/" + goarch
+}
+
+// runBenchmarks runs the given benchmarks on an instrumented copy of
+// fileName's package and returns their results, with the platform and Go
+// release as a stamp.
+func runBenchmarks(fileName string, names []string) ([]benchmarkResult, string, error) {
+	tmpDir, err := os.MkdirTemp("", "go2md-bench")
+	if err != nil {
+		return nil, "", err
+	}
+	defer os.RemoveAll(tmpDir)
+	goFiles, err := instrumentedCopy(fileName, tmpDir, map[string]string{})
+	if err != nil {
+		return nil, "", err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), benchmarkTimeout)
+	defer cancel()
+
+	args := []string{"test", "-count=1", "-run", "^$", "-bench", "^(" + strings.Join(names, "|") + ")$", "-benchmem"}
+	var output bytes.Buffer
+	cmd := exec.CommandContext(ctx, "go", append(args, goFiles...)...)
+	cmd.Dir = tmpDir
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	if err := cmd.Run(); err != nil {
+		return nil, "", fmt.Errorf("%v\n%s", err, output.String())
+	}
+	version, err := exec.Command("go", "env", "GOVERSION").Output()
+	if err != nil {
+		return nil, "", err
+	}
+	results, platform := parseBenchmarks(output.String())
+	return results, platform + ", " + strings.TrimSpace(string(version)), nil
+}
+
+// benchmarkTable returns the block tabulating results, one row per
+// result with its cells separated by tabs, under the header row.
+func benchmarkTable(results []benchmarkResult, stamp string) block {
+	table := block{Kind: benchmarkBlock, Label: stamp, Lines: []string{strings.Join(benchmarkColumns, "\t")}}
+	for _, r := range results {
+		table.Lines = append(table.Lines, strings.Join([]string{r.Name, r.NsPerOp, r.BytesPerOp, r.AllocsPerOp}, "\t"))
+	}
+	return table
+}
+
+// applyBenchmarks runs the benchmarks of doc and adds the table of the
+// results of each section at its end, before the next heading.
+func applyBenchmarks(doc *document) error {
+	funcs, err := benchmarkFuncs(doc.File)
+	if err != nil || len(funcs) == 0 {
+		return err
+	}
+	found, err := headings(doc.File)
+	if err != nil {
+		return err
+	}
+	names := []string{}
+	sections := map[string]int{}
+	for line, name := range funcs {
+		names = append(names, name)
+		sections[name] = 0
+		for _, h := range found {
+			if h.Line <= line && h.Line > sections[name] {
+				sections[name] = h.Line
+			}
+		}
+	}
+	results, stamp, err := runBenchmarks(doc.File, names)
+	if err != nil {
+		return err
+	}
+	tables := map[int][]benchmarkResult{}
+	for _, r := range results {
+		// Sub-benchmarks belong with their parent
+		name, _, _ := strings.Cut(r.Name, "/")
+		section, found := sections[name]
+		if found {
+			tables[section] = append(tables[section], r)
+		}
+	}
+	table := func(section int) []block {
+		if len(tables[section]) == 0 {
+			return nil
+		}
+		return []block{benchmarkTable(tables[section], stamp)}
+	}
+
+	blocks := []block{}
+	section := 0
+	for _, b := range doc.Blocks {
+		if b.Kind == headingBlock {
+			blocks = append(blocks, table(section)...)
+			section = b.Line
+		}
+		blocks = append(blocks, b)
+	}
+	doc.Blocks = append(blocks, table(section)...)
+	return nil
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_ParseBenchmarks(t *testing.T) {
+	output := `goos: linux
+goarch: amd64
+cpu: Intel(R) Xeon(R) CPU @ 2.20GHz
+Benchmark_Mutex-8    	70581446	        16.91 ns/op	       0 B/op	       0 allocs/op
+Benchmark_Slice/64-8 	32786503	        36.51 ns/op	      71 B/op	       1 allocs/op
+PASS
+ok  	command-line-arguments	2.618s
+`
+	results, platform := parseBenchmarks(output)
+	if platform != "linux/amd64" {
+		t.Errorf("platform = %q; want %q", platform, "linux/amd64")
+	}
+	want := []benchmarkResult{
+		{Name: "Benchmark_Mutex", NsPerOp: "16.91", BytesPerOp: "0", AllocsPerOp: "0"},
+		{Name: "Benchmark_Slice/64", NsPerOp: "36.51", BytesPerOp: "71", AllocsPerOp: "1"},
+	}
+	if len(results) != len(want) {
+		t.Fatalf("parseBenchmarks returned %d results; want %d", len(results), len(want))
+	}
+	for i := range want {
+		if results[i] != want[i] {
+			t.Errorf("result %d = %+v; want %+v", i, results[i], want[i])
+		}
+	}
+}
+
+func Test_ApplyBenchmarks(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "chapter_test.go")
+	source := `// # Chapter
+package chapter
+
+import "testing"
+
+// ## Counting
+func Test_Count(t *testing.T) {
+}
+
+// ## Summing
+func Benchmark_Sum(b *testing.B) {
+	sum := 0
+	for i := 0; i < b.N; i++ {
+		sum = sum + i
+	}
+}
+
+// ## Closing
+// No benchmarks here.
+`
+	if err := os.WriteFile(fileName, []byte(source), 0644); err != nil {
+		t.Fatal(err)
+	}
+	doc := file2doc(fileName)
+	if err := applyBenchmarks(doc); err != nil {
+		t.Fatal(err)
+	}
+	tables, section, previous := 0, "", block{}
+	for _, b := range doc.Blocks {
+		if b.Kind == headingBlock {
+			section = b.Lines[0]
+			if section == "Closing" && previous.Kind != benchmarkBlock {
+				t.Errorf("Closing heading follows %+v; want the table", previous)
+			}
+		}
+		if b.Kind == benchmarkBlock {
+			tables++
+			if section != "Summing" {
+				t.Errorf("table in section %q; want Summing", section)
+			}
+			if len(b.Lines) != 2 || !strings.HasPrefix(b.Lines[1], "Benchmark_Sum\t") {
+				t.Errorf("table = %q; want a header and a row for Benchmark_Sum", b.Lines)
+			}
+		}
+		previous = b
+	}
+	if tables != 1 {
+		t.Errorf("applyBenchmarks added %d tables; want 1", tables)
+	}
+}
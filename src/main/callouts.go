@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// With -callouts, trailing comments are lifted out of code lines: each is
+// replaced by a comment holding only a numbered marker, so that the code
+// stays valid Go, and a list under the snippet explains the markers.
+// Numbering starts over with every snippet.
+
+var calloutMode = false
+
+// calloutMarker returns the marker of the nth callout, counting from one.
+func calloutMarker(n int) string {
+	if n <= 20 {
+		return string(rune('①' + n - 1))
+	}
+	return fmt.Sprintf("(%d)", n)
+}
+
+// calloutNumber returns the number of a callout marker.
+func calloutNumber(marker string) (int, bool) {
+	for n := 1; n <= 20; n++ {
+		if marker == calloutMarker(n) {
+			return n, true
+		}
+	}
+	var n int
+	if _, err := fmt.Sscanf(marker, "(%d)", &n); err != nil {
+		return 0, false
+	}
+	return n, true
+}
+
+// trailingComment splits line into its code and the text of its trailing
+// line comment, if it has both. Raw tells whether the line starts inside
+// a raw string literal, and is updated for the next line.
+func trailingComment(line string, raw *bool) (string, string, bool) {
+	quote := byte(0)
+	if *raw {
+		quote = '`'
+	}
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote == '`':
+			if c == '`' {
+				quote = 0
+			}
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'' || c == '`':
+			quote = c
+		case c == '/' && i+1 < len(line) && line[i+1] == '*':
+			if end := strings.Index(line[i+2:], "*/"); end >= 0 {
+				i = i + 2 + end + 1
+			} else {
+				i = len(line)
+			}
+		case c == '/' && i+1 < len(line) && line[i+1] == '/':
+			*raw = false
+			code := strings.TrimRight(line[:i], " \t")
+			comment := strings.TrimSpace(line[i+2:])
+			if strings.TrimSpace(code) == "" || comment == "" {
+				return line, "", false
+			}
+			return code, comment, true
+		}
+	}
+	*raw = quote == '`'
+	return line, "", false
+}
+
+// applyCallouts lifts the trailing comments of the snippets of doc into
+// callout lists placed right under them.
+func applyCallouts(doc *document) {
+	blocks := []block{}
+	for _, b := range doc.Blocks {
+		if b.Kind != codeBlock {
+			blocks = append(blocks, b)
+			continue
+		}
+		raw := false
+		lines := []string{}
+		callouts := []string{}
+		for _, line := range b.Lines {
+			code, comment, found := trailingComment(line, &raw)
+			if statement, rest, ok := assertionComment(comment); found && ok {
+				// The expected value stays next to the assertion
+				code = code + " // " + statement
+				comment = rest
+			}
+			if found && comment != "" {
+				marker := calloutMarker(len(callouts) + 1)
+				code = code + " // " + marker
+				callouts = append(callouts, marker+" "+comment)
+			}
+			lines = append(lines, code)
+		}
+		b.Lines = lines
+		blocks = append(blocks, b)
+		if len(callouts) > 0 {
+			blocks = append(blocks, block{Kind: calloutBlock, Lines: callouts})
+		}
+	}
+	doc.Blocks = blocks
+}
+
+// assertionComment splits a comment that gives the expected value of an
+// assertion, as the comment symbol set writes it, into that value along
+// with its symbol and the comment the line had of its own, if any.
+func assertionComment(comment string) (string, string, bool) {
+	if !symbols.Comment {
+		return "", "", false
+	}
+	if !strings.HasPrefix("// "+comment, symbols.Equal+" ") && !strings.HasPrefix("// "+comment, symbols.NotEqual+" ") {
+		return "", "", false
+	}
+	quote := byte(0)
+	for i := 0; i < len(comment); i++ {
+		c := comment[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote != '`' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'' || c == '`':
+			quote = c
+		case strings.HasPrefix(comment[i:], "; "):
+			return comment[:i], comment[i+2:], true
+		}
+	}
+	return comment, "", true
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_TrailingComment(t *testing.T) {
+	raw := false
+	for _, c := range []struct {
+		line    string
+		code    string
+		comment string
+	}{
+		{"pending++ // Add new pending goroutine: pending++", "pending++", "Add new pending goroutine: pending++"},
+		{`url := "http://go.dev" // not a comment: //`, `url := "http://go.dev"`, "not a comment: //"},
+		{`r := '/' /* block */ // rune`, `r := '/' /* block */`, "rune"},
+		{"// a whole line comment", "// a whole line comment", ""},
+		{"s := `raw // text", "s := `raw // text", ""},
+		{"still // raw`", "still // raw`", ""},
+		{"x := 1 //", "x := 1 //", ""},
+		{"y := 2 // two", "y := 2", "two"},
+	} {
+		code, comment, _ := trailingComment(c.line, &raw)
+		if code != c.code || comment != c.comment {
+			t.Errorf("trailingComment(%q) = %q, %q; want %q, %q", c.line, code, comment, c.code, c.comment)
+		}
+	}
+}
+
+func Test_CalloutsStayValidGo(t *testing.T) {
+	doc := &document{Blocks: []block{{Kind: codeBlock, Lines: []string{
+		"pending := 0",
+		"pending++ // Add new pending goroutine: pending++",
+		"url := \"http://go.dev\" // not a comment: //",
+		"s := `raw // text",
+		"still raw` // posted by platter(2)",
+		"_, _ = url, s",
+	}}}}
+	applyCallouts(doc)
+	if len(doc.Blocks) != 2 || doc.Blocks[1].Kind != calloutBlock || len(doc.Blocks[1].Lines) != 3 {
+		t.Fatalf("applyCallouts gave %+v; want a snippet and three callouts", doc.Blocks)
+	}
+	if line := doc.Blocks[0].Lines[1]; line != "pending++ // ①" {
+		t.Errorf("callout line = %q; want %q", line, "pending++ // ①")
+	}
+	source := "package p\n\nfunc _() {\n" + strings.Join(doc.Blocks[0].Lines, "\n") + "\n}\n"
+	if _, err := parser.ParseFile(token.NewFileSet(), "callouts.go", source, 0); err != nil {
+		t.Errorf("snippet with callouts does not parse: %v\n%s", err, source)
+	}
+}
+
+func Test_CalloutsWithCommentSymbols(t *testing.T) {
+	defer func(previous symbolSet) { symbols = previous }(symbols)
+	symbols = symbolSets["comment"]
+	fileName := filepath.Join(t.TempDir(), "chapter_test.go")
+	source := `package chapter
+
+// ## Copies
+func Test_Copy(t *testing.T) {
+	a := 0
+	b := a // b is a copy
+	b++
+	assert.Equal(t, 0, a) // a is the original value
+	assert.Equal(t, "; ", "; ")
+}
+`
+	if err := os.WriteFile(fileName, []byte(source), 0644); err != nil {
+		t.Fatal(err)
+	}
+	doc := file2doc(fileName)
+	applyCallouts(doc)
+	code, callouts := []string{}, []string{}
+	for _, b := range doc.Blocks {
+		switch b.Kind {
+		case codeBlock:
+			code = append(code, b.Lines...)
+		case calloutBlock:
+			callouts = append(callouts, b.Lines...)
+		}
+	}
+	for _, want := range []string{"b := a // ①", "a // => 0 // ②", `"; " // => "; "`} {
+		if !strings.Contains(strings.Join(code, "\n"), want) {
+			t.Errorf("snippet\n%s\nlacks %q", strings.Join(code, "\n"), want)
+		}
+	}
+	want := []string{"① b is a copy", "② a is the original value"}
+	if strings.Join(callouts, "\n") != strings.Join(want, "\n") {
+		t.Errorf("callouts = %q; want %q", callouts, want)
+	}
+}
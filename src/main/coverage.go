@@ -0,0 +1,273 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The coverage report tells which exported functions, methods and types
+// of the standard library packages used by the chapters are demonstrated
+// in rendered code. The exported API of a package is read from its
+// sources in GOROOT. How commonly an undemonstrated name is used is
+// estimated by counting the references to it across GOROOT itself;
+// methods cannot be told apart without type checking, so only functions
+// and types are counted.
+
+const defaultCoverageTop = 5
+
+func coverageCommand(args []string) int {
+	top := defaultCoverageTop
+	coverageFiles := []string{}
+	skip := false
+	for index, arg := range args {
+		if arg == "-n" {
+			if index >= len(args)-1 {
+				fmt.Printf("No arguments after -n\n")
+				return 1
+			}
+			n, err := strconv.Atoi(args[index+1])
+			if err != nil {
+				fmt.Printf("Invalid value for -n: %s\n", args[index+1])
+				return 1
+			}
+			top = n
+			skip = true
+		} else if !skip {
+			coverageFiles = append(coverageFiles, arg)
+		} else {
+			skip = false
+		}
+	}
+	if len(coverageFiles) == 0 {
+		fmt.Printf("Usage:\n\n")
+		fmt.Printf("    go2md coverage [-n <TOP>] <file_1> <file_2> ... <file_n>\n\n")
+		return 0
+	}
+
+	demonstrated := map[string]map[string]bool{}
+	for _, fileName := range coverageFiles {
+		if err := demonstratedAPI(fileName, demonstrated); err != nil {
+			fmt.Printf("Unable to check file %s\n%v\n", fileName, err)
+			return 1
+		}
+	}
+	paths := []string{}
+	for path := range demonstrated {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	usage := stdlibUsage()
+	totalAPI, totalDemonstrated := 0, 0
+	fmt.Printf("%-16s %12s %8s\n", "Package", "Demonstrated", "Coverage")
+	report := ""
+	for _, path := range paths {
+		api, err := exportedAPI(path)
+		if err != nil {
+			fmt.Printf("Unable to read package %s\n%v\n", path, err)
+			return 1
+		}
+		count := 0
+		missing := []string{}
+		for _, name := range api {
+			if demonstrated[path][name] {
+				count++
+			} else if usage[path+"."+name] > 0 {
+				missing = append(missing, name)
+			}
+		}
+		totalAPI, totalDemonstrated = totalAPI+len(api), totalDemonstrated+count
+		fmt.Printf("%-16s %5d / %-4d %7.1f%%\n", path, count, len(api), percentage(count, len(api)))
+
+		sort.SliceStable(missing, func(a, b int) bool {
+			return usage[path+"."+missing[a]] > usage[path+"."+missing[b]]
+		})
+		if len(missing) > top {
+			missing = missing[:top]
+		}
+		if len(missing) > 0 {
+			report = report + fmt.Sprintf("\n%s:\n", path)
+			for _, name := range missing {
+				report = report + fmt.Sprintf("    %-32s %6d uses in GOROOT\n", name, usage[path+"."+name])
+			}
+		}
+	}
+	fmt.Printf("%-16s %5d / %-4d %7.1f%%\n", "Total", totalDemonstrated, totalAPI, percentage(totalDemonstrated, totalAPI))
+	if report != "" {
+		fmt.Printf("\nMost commonly used but not demonstrated:\n%s", report)
+	}
+	return 0
+}
+
+func percentage(part int, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(part) * 100 / float64(total)
+}
+
+// isStdlib tells whether an import path belongs to the standard library.
+func isStdlib(path string) bool {
+	first := strings.Split(path, "/")[0]
+	return !strings.Contains(first, ".") && !strings.Contains(path, "internal")
+}
+
+// demonstratedAPI adds the standard library functions, methods and types
+// referenced in the rendered code of fileName to demonstrated, by import
+// path. Methods are named Type.Method.
+func demonstratedAPI(fileName string, demonstrated map[string]map[string]bool) error {
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return err
+	}
+	isCode, _ := renderedCode(strings.Split(string(data), "\n"))
+	fset, _, pkg, info, err := typeCheckPackage(fileName)
+	if err != nil {
+		return err
+	}
+	for ident, obj := range info.Uses {
+		position := fset.Position(ident.Pos())
+		if position.Filename != fileName || !isCode[position.Line] || obj.Pkg() == nil || obj.Pkg() == pkg || !isStdlib(obj.Pkg().Path()) || !obj.Exported() {
+			continue
+		}
+		name := ""
+		switch o := obj.(type) {
+		case *types.TypeName:
+			name = o.Name()
+		case *types.Func:
+			name = o.Name()
+			if recv := o.Type().(*types.Signature).Recv(); recv != nil {
+				t := recv.Type()
+				if pointer, ok := t.(*types.Pointer); ok {
+					t = pointer.Elem()
+				}
+				named, ok := t.(*types.Named)
+				if !ok || !named.Obj().Exported() || types.IsInterface(named) {
+					// Interface methods are not part of the API read from
+					// the sources, and neither are those promoted from
+					// unexported types
+					continue
+				}
+				name = named.Obj().Name() + "." + name
+			}
+		default:
+			continue
+		}
+		path := obj.Pkg().Path()
+		if demonstrated[path] == nil {
+			demonstrated[path] = map[string]bool{}
+		}
+		demonstrated[path][name] = true
+	}
+	return nil
+}
+
+// exportedAPI returns the exported functions, methods (as Type.Method) and
+// types of the standard library package path, read from its sources.
+func exportedAPI(path string) ([]string, error) {
+	pkg, err := build.Import(path, "", 0)
+	if err != nil {
+		return nil, err
+	}
+	fset := token.NewFileSet()
+	found := map[string]bool{}
+	for _, goFile := range pkg.GoFiles {
+		file, err := parser.ParseFile(fset, filepath.Join(pkg.Dir, goFile), nil, parser.SkipObjectResolution)
+		if err != nil {
+			return nil, err
+		}
+		for _, decl := range file.Decls {
+			switch d := decl.(type) {
+			case *ast.FuncDecl:
+				if !d.Name.IsExported() {
+					continue
+				}
+				if d.Recv == nil {
+					found[d.Name.Name] = true
+					continue
+				}
+				if receiver := receiverName(d.Recv.List[0].Type); ast.IsExported(receiver) {
+					found[receiver+"."+d.Name.Name] = true
+				}
+			case *ast.GenDecl:
+				for _, spec := range d.Specs {
+					if typeSpec, ok := spec.(*ast.TypeSpec); ok && typeSpec.Name.IsExported() {
+						found[typeSpec.Name.Name] = true
+					}
+				}
+			}
+		}
+	}
+	api := []string{}
+	for name := range found {
+		api = append(api, name)
+	}
+	sort.Strings(api)
+	return api, nil
+}
+
+// receiverName returns the type name of a method receiver such as *T or
+// T[K].
+func receiverName(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.StarExpr:
+		return receiverName(e.X)
+	case *ast.IndexExpr:
+		return receiverName(e.X)
+	case *ast.IndexListExpr:
+		return receiverName(e.X)
+	case *ast.Ident:
+		return e.Name
+	}
+	return ""
+}
+
+// stdlibUsage counts the references to package level names, as
+// path.Name, across the non-test sources in GOROOT.
+func stdlibUsage() map[string]int {
+	usage := map[string]int{}
+	root := filepath.Join(build.Default.GOROOT, "src")
+	filepath.Walk(root, func(fileName string, fileInfo os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if fileInfo.IsDir() && (fileInfo.Name() == "testdata" || fileInfo.Name() == "vendor") {
+			return filepath.SkipDir
+		}
+		if !strings.HasSuffix(fileName, ".go") || strings.HasSuffix(fileName, "_test.go") {
+			return nil
+		}
+		file, err := parser.ParseFile(token.NewFileSet(), fileName, nil, parser.SkipObjectResolution)
+		if err != nil {
+			return nil
+		}
+		imports := map[string]string{}
+		for _, spec := range file.Imports {
+			path, _ := strconv.Unquote(spec.Path.Value)
+			name := filepath.Base(path)
+			if spec.Name != nil {
+				name = spec.Name.Name
+			}
+			imports[name] = path
+		}
+		ast.Inspect(file, func(n ast.Node) bool {
+			if selector, ok := n.(*ast.SelectorExpr); ok {
+				if x, ok := selector.X.(*ast.Ident); ok && imports[x.Name] != "" {
+					usage[imports[x.Name]+"."+selector.Sel.Name]++
+				}
+			}
+			return true
+		})
+		return nil
+	})
+	return usage
+}
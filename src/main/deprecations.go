@@ -0,0 +1,202 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Standard library APIs are deprecated by a paragraph starting with
+// "Deprecated:" in their doc comment, which is read from their sources in
+// GOROOT. A section whose rendered code uses one gets a warning naming
+// the replacement: the first API the paragraph links to, or else the
+// paragraph itself. In strict mode, any such use fails the conversion.
+
+var strictDeprecations = false
+
+// A deprecation is a use of a deprecated API on a line.
+type deprecation struct {
+	Line    int
+	Name    string
+	Message string
+}
+
+// deprecatedPackages caches the deprecation paragraphs of each package
+// by member name, with the empty name standing for the package itself.
+var deprecatedPackages = map[string]map[string]string{}
+
+var replacementPattern = regexp.MustCompile(`\[(\w+(?:/\w+)*\.\w+(?:\.\w+)?)\]`)
+
+func (d deprecation) Text() string {
+	if replacement := replacementPattern.FindStringSubmatch(d.Message); replacement != nil {
+		return fmt.Sprintf("`%s` is deprecated: use `%s` instead.", d.Name, replacement[1])
+	}
+	message := strings.TrimSpace(strings.TrimPrefix(d.Message, "Deprecated:"))
+	message = strings.NewReplacer("[", "`", "]", "`").Replace(message)
+	return fmt.Sprintf("`%s` is deprecated: %s", d.Name, message)
+}
+
+// deprecatedAPI returns the deprecation paragraphs of the exported members
+// of the standard library package path, read from its sources.
+func deprecatedAPI(path string) (map[string]string, error) {
+	if found, cached := deprecatedPackages[path]; cached {
+		return found, nil
+	}
+	found := map[string]string{}
+	deprecatedPackages[path] = found
+	pkg, err := build.Import(path, "", 0)
+	if err != nil {
+		return nil, err
+	}
+	fset := token.NewFileSet()
+	add := func(name string, doc *ast.CommentGroup) {
+		if doc == nil {
+			return
+		}
+		for _, paragraph := range strings.Split(doc.Text(), "\n\n") {
+			if strings.HasPrefix(paragraph, "Deprecated:") {
+				found[name] = strings.Join(strings.Fields(paragraph), " ")
+			}
+		}
+	}
+	for _, goFile := range pkg.GoFiles {
+		file, err := parser.ParseFile(fset, filepath.Join(pkg.Dir, goFile), nil, parser.ParseComments|parser.SkipObjectResolution)
+		if err != nil {
+			return nil, err
+		}
+		add("", file.Doc)
+		for _, decl := range file.Decls {
+			switch d := decl.(type) {
+			case *ast.FuncDecl:
+				if d.Recv == nil {
+					add(d.Name.Name, d.Doc)
+				} else {
+					add(receiverName(d.Recv.List[0].Type)+"."+d.Name.Name, d.Doc)
+				}
+			case *ast.GenDecl:
+				for _, spec := range d.Specs {
+					switch s := spec.(type) {
+					case *ast.TypeSpec:
+						add(s.Name.Name, d.Doc)
+						add(s.Name.Name, s.Doc)
+					case *ast.ValueSpec:
+						for _, name := range s.Names {
+							if len(d.Specs) == 1 {
+								add(name.Name, d.Doc)
+							}
+							add(name.Name, s.Doc)
+						}
+					}
+				}
+			}
+		}
+	}
+	return found, nil
+}
+
+// deprecatedUses returns the uses of deprecated standard library APIs in
+// the rendered code of fileName, in line order.
+func deprecatedUses(fileName string) ([]deprecation, error) {
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	isCode, _ := renderedCode(strings.Split(string(data), "\n"))
+	fset, file, pkg, info, err := typeCheckPackage(fileName)
+	if err != nil {
+		return nil, err
+	}
+	qualified := qualifiedIdents(file, info)
+	uses := []deprecation{}
+	for ident, obj := range info.Uses {
+		position := fset.Position(ident.Pos())
+		if obj == nil || obj.Pkg() == nil || obj.Pkg() == pkg || position.Filename != fileName || !isCode[position.Line] {
+			continue
+		}
+		path, name := obj.Pkg().Path(), memberName(obj)
+		if qualifiedPath, ok := qualified[ident]; ok {
+			path, name = qualifiedPath, obj.Name()
+		}
+		if !isStdlib(path) {
+			continue
+		}
+		deprecated, err := deprecatedAPI(path)
+		if err != nil {
+			return nil, err
+		}
+		shown := name
+		if qualified[ident] != "" {
+			shown = filepath.Base(path) + "." + name
+		}
+		if message, found := deprecated[name]; found {
+			uses = append(uses, deprecation{Line: position.Line, Name: shown, Message: message})
+		} else if message, found := deprecated[""]; found && qualified[ident] != "" {
+			uses = append(uses, deprecation{Line: position.Line, Name: shown, Message: message})
+		}
+	}
+	sort.Slice(uses, func(a, b int) bool {
+		if uses[a].Line != uses[b].Line {
+			return uses[a].Line < uses[b].Line
+		}
+		return uses[a].Name < uses[b].Name
+	})
+	return uses, nil
+}
+
+// applyDeprecations adds a warning under the heading, and badge, of every
+// section of doc that uses deprecated APIs, naming each of them once. It
+// returns the uses found.
+func applyDeprecations(doc *document) ([]deprecation, error) {
+	uses, err := deprecatedUses(doc.File)
+	if err != nil {
+		return nil, err
+	}
+	found, err := headings(doc.File)
+	if err != nil {
+		return nil, err
+	}
+	warnings := map[int][]string{}
+	named := map[int]map[string]bool{}
+	for _, use := range uses {
+		section := 0
+		for _, h := range found {
+			if h.Line <= use.Line {
+				section = h.Line
+			}
+		}
+		if named[section] == nil {
+			named[section] = map[string]bool{}
+		}
+		if !named[section][use.Name] {
+			named[section][use.Name] = true
+			warnings[section] = append(warnings[section], use.Text())
+		}
+	}
+	warning := func(section int) []block {
+		if len(warnings[section]) == 0 {
+			return nil
+		}
+		return []block{{Kind: admonitionBlock, Label: "Warning", Lines: warnings[section]}}
+	}
+
+	blocks := warning(0)
+	section := 0
+	for i, b := range doc.Blocks {
+		blocks = append(blocks, b)
+		if b.Kind == headingBlock {
+			section = b.Line
+		}
+		if (b.Kind == headingBlock || b.Kind == badgeBlock) && (i == len(doc.Blocks)-1 || doc.Blocks[i+1].Kind != badgeBlock) {
+			blocks = append(blocks, warning(section)...)
+		}
+	}
+	doc.Blocks = blocks
+	return uses, nil
+}
@@ -0,0 +1,472 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"html"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// A //go2md:diagram directive adds a mermaid sequence diagram under the
+// snippet that follows it. Its arguments are mermaid statements, one per
+// directive line:
+//
+//	//go2md:diagram participant W as worker
+//	//go2md:diagram T->>W: job
+//	//go2md:diagram Note over W: works
+//
+// A bare //go2md:diagram placed right before a function derives the
+// diagram from it instead: the function and each goroutine it starts are
+// participants, channel receives are messages from the matching send, and
+// labelled steps, such as execOrder = execOrder + "B", are notes. When an
+// assertion gives the expected order of the labels, as in
+// assert.Equal(t, "ABCDE", execOrder), steps are laid out in that order,
+// each participant's other steps next to its labels, and labels missing
+// from it are left out; otherwise in source order.
+// Only the subset of mermaid written here is understood, which is also
+// what the HTML renderer draws as inline SVG.
+const diagramDirective = "//go2md:diagram"
+
+type diagramRequest struct {
+	Statements []string
+	Target     string
+}
+
+type participant struct {
+	ID   string
+	Name string
+}
+
+// A diagramStep is a message From one participant To another, or a note
+// over the participants from From to To.
+type diagramStep struct {
+	From   string
+	To     string
+	Text   string
+	Note   bool
+	Dashed bool
+}
+
+type diagram struct {
+	Participants []participant
+	Steps        []diagramStep
+}
+
+// parseDiagramDirective adds the statement of a //go2md:diagram line, if
+// any, to request.
+func parseDiagramDirective(line string, request *diagramRequest) (*diagramRequest, error) {
+	if request == nil {
+		request = &diagramRequest{}
+	}
+	statement := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), diagramDirective))
+	if statement == "" {
+		return request, nil
+	}
+	if err := (&diagram{}).parse(statement); err != nil {
+		return nil, err
+	}
+	request.Statements = append(request.Statements, statement)
+	return request, nil
+}
+
+// requestedDiagram returns the mermaid source of the diagram requested,
+// or of the one derived from the target function.
+func requestedDiagram(fileName string, request *diagramRequest) (block, error) {
+	d := &diagram{}
+	if len(request.Statements) > 0 {
+		for _, statement := range request.Statements {
+			if err := d.parse(statement); err != nil {
+				return block{}, err
+			}
+		}
+	} else {
+		var err error
+		d, err = deriveDiagram(fileName, request.Target)
+		if err != nil {
+			return block{}, err
+		}
+	}
+	return block{Kind: diagramBlock, Lines: d.mermaid()}, nil
+}
+
+func (d *diagram) add(id string) {
+	for _, p := range d.Participants {
+		if p.ID == id {
+			return
+		}
+	}
+	d.Participants = append(d.Participants, participant{ID: id, Name: id})
+}
+
+// parse adds a mermaid statement to d.
+func (d *diagram) parse(statement string) error {
+	statement = strings.TrimSpace(statement)
+	switch {
+	case statement == "" || statement == "sequenceDiagram" || strings.HasPrefix(statement, "%%"):
+		return nil
+	case strings.HasPrefix(statement, "participant ") || strings.HasPrefix(statement, "actor "):
+		_, rest, _ := strings.Cut(statement, " ")
+		id, name, found := strings.Cut(strings.TrimSpace(rest), " as ")
+		id = strings.TrimSpace(id)
+		if !found {
+			name = id
+		}
+		d.add(id)
+		for i := range d.Participants {
+			if d.Participants[i].ID == id {
+				d.Participants[i].Name = strings.TrimSpace(name)
+			}
+		}
+		return nil
+	case strings.HasPrefix(statement, "Note "):
+		where, text, found := strings.Cut(strings.TrimPrefix(statement, "Note "), ":")
+		if !found {
+			return fmt.Errorf("note without text: %s", statement)
+		}
+		for _, position := range []string{"over ", "left of ", "right of "} {
+			if ids, found := strings.CutPrefix(where, position); found {
+				from, to, _ := strings.Cut(ids, ",")
+				from, to = strings.TrimSpace(from), strings.TrimSpace(to)
+				if to == "" {
+					to = from
+				}
+				d.add(from)
+				d.add(to)
+				d.Steps = append(d.Steps, diagramStep{From: from, To: to, Text: strings.TrimSpace(text), Note: true})
+				return nil
+			}
+		}
+		return fmt.Errorf("unsupported note: %s", statement)
+	}
+	for _, arrow := range []string{"-->>", "->>", "-->", "->"} {
+		if from, rest, found := strings.Cut(statement, arrow); found {
+			to, text, found := strings.Cut(rest, ":")
+			if !found {
+				return fmt.Errorf("message without text: %s", statement)
+			}
+			from, to = strings.TrimSpace(from), strings.TrimSpace(to)
+			d.add(from)
+			d.add(to)
+			d.Steps = append(d.Steps, diagramStep{From: from, To: to, Text: strings.TrimSpace(text), Dashed: strings.HasPrefix(arrow, "--")})
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported diagram statement: %s", statement)
+}
+
+func (d *diagram) mermaid() []string {
+	lines := []string{"sequenceDiagram"}
+	for _, p := range d.Participants {
+		if p.Name == p.ID {
+			lines = append(lines, "    participant "+p.ID)
+		} else {
+			lines = append(lines, "    participant "+p.ID+" as "+p.Name)
+		}
+	}
+	for _, s := range d.Steps {
+		switch {
+		case s.Note && s.From == s.To:
+			lines = append(lines, fmt.Sprintf("    Note over %s: %s", s.From, s.Text))
+		case s.Note:
+			lines = append(lines, fmt.Sprintf("    Note over %s,%s: %s", s.From, s.To, s.Text))
+		case s.Dashed:
+			lines = append(lines, fmt.Sprintf("    %s-->>%s: %s", s.From, s.To, s.Text))
+		default:
+			lines = append(lines, fmt.Sprintf("    %s->>%s: %s", s.From, s.To, s.Text))
+		}
+	}
+	return lines
+}
+
+// A diagramEvent is a labelled step, a send or a receive of a participant.
+type diagramEvent struct {
+	Participant string
+	Pos         token.Pos
+	Label       string
+	Send        bool
+	Receive     bool
+	Channel     string
+	Text        string
+	done        bool
+	matched     bool
+}
+
+// deriveDiagram derives a diagram from the function target of fileName.
+func deriveDiagram(fileName string, target string) (*diagram, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, fileName, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+	var fn *ast.FuncDecl
+	for _, decl := range file.Decls {
+		if funcDecl, ok := decl.(*ast.FuncDecl); ok && funcDecl.Name.Name == target {
+			fn = funcDecl
+		}
+	}
+	if fn == nil || fn.Body == nil {
+		return nil, fmt.Errorf("no function %q to derive a diagram from", target)
+	}
+	source := func(node ast.Node) string {
+		var text strings.Builder
+		printer.Fprint(&text, fset, node)
+		return text.String()
+	}
+
+	// The variable labels are appended to, and the order an assertion
+	// expects them in
+	appended := map[string]bool{}
+	ast.Inspect(fn.Body, func(n ast.Node) bool {
+		if assign, ok := n.(*ast.AssignStmt); ok && len(assign.Lhs) == 1 && len(assign.Rhs) == 1 {
+			if strings.HasPrefix(source(assign.Rhs[0]), source(assign.Lhs[0])+" + ") {
+				appended[source(assign.Lhs[0])] = true
+			}
+		}
+		return true
+	})
+	orderVar, order := "", ""
+	ast.Inspect(fn.Body, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok || source(call.Fun) != "assert.Equal" || len(call.Args) != 3 {
+			return true
+		}
+		expected, isLiteral := call.Args[1].(*ast.BasicLit)
+		if isLiteral && expected.Kind == token.STRING && appended[source(call.Args[2])] {
+			orderVar = source(call.Args[2])
+			order, _ = strconv.Unquote(expected.Value)
+		}
+		return true
+	})
+	if orderVar == "" {
+		// Labels without an expected order
+		for name := range appended {
+			if orderVar == "" || name < orderVar {
+				orderVar = name
+			}
+		}
+	}
+
+	d := &diagram{}
+	d.Participants = append(d.Participants, participant{ID: "T", Name: target})
+	events := []*diagramEvent{}
+	var walk func(node ast.Node, owner string)
+	// Function literals bound to a name that a go statement starts belong
+	// to the goroutine
+	started := map[string]*ast.FuncLit{}
+	ast.Inspect(fn.Body, func(n ast.Node) bool {
+		if s, ok := n.(*ast.GoStmt); ok {
+			started[source(s.Call.Fun)] = nil
+		}
+		return true
+	})
+	ast.Inspect(fn.Body, func(n ast.Node) bool {
+		if assign, ok := n.(*ast.AssignStmt); ok && len(assign.Lhs) == 1 && len(assign.Rhs) == 1 {
+			if literal, ok := assign.Rhs[0].(*ast.FuncLit); ok {
+				if _, found := started[source(assign.Lhs[0])]; found {
+					started[source(assign.Lhs[0])] = literal
+				}
+			}
+		}
+		return true
+	})
+	walk = func(node ast.Node, owner string) {
+		ast.Inspect(node, func(n ast.Node) bool {
+			switch s := n.(type) {
+			case *ast.FuncLit:
+				for _, literal := range started {
+					if literal == s {
+						return false
+					}
+				}
+			case *ast.GoStmt:
+				id := fmt.Sprintf("G%d", len(d.Participants))
+				if literal, ok := s.Call.Fun.(*ast.FuncLit); ok {
+					d.Participants = append(d.Participants, participant{ID: id, Name: fmt.Sprintf("goroutine %d", len(d.Participants))})
+					walk(literal.Body, id)
+				} else if literal := started[source(s.Call.Fun)]; literal != nil {
+					d.Participants = append(d.Participants, participant{ID: id, Name: source(s.Call.Fun)})
+					walk(literal.Body, id)
+				} else {
+					d.Participants = append(d.Participants, participant{ID: id, Name: source(s.Call.Fun)})
+				}
+				for _, arg := range s.Call.Args {
+					walk(arg, owner)
+				}
+				return false
+			case *ast.AssignStmt:
+				if len(s.Lhs) != 1 || len(s.Rhs) != 1 || source(s.Lhs[0]) != orderVar {
+					return true
+				}
+				if literal, ok := s.Rhs[0].(*ast.BasicLit); ok && s.Tok == token.DEFINE && literal.Kind == token.STRING {
+					label, _ := strconv.Unquote(literal.Value)
+					events = append(events, &diagramEvent{Participant: owner, Pos: s.Pos(), Label: label})
+				} else if label, found := strings.CutPrefix(source(s.Rhs[0]), orderVar+" + "); found {
+					if unquoted, err := strconv.Unquote(label); err == nil {
+						label = unquoted
+					}
+					events = append(events, &diagramEvent{Participant: owner, Pos: s.Pos(), Label: label})
+				}
+			case *ast.SendStmt:
+				events = append(events, &diagramEvent{Participant: owner, Pos: s.Pos(), Send: true, Channel: source(s.Chan), Text: source(s)})
+			case *ast.UnaryExpr:
+				if s.Op == token.ARROW {
+					events = append(events, &diagramEvent{Participant: owner, Pos: s.Pos(), Receive: true, Channel: source(s.X), Text: source(s)})
+				}
+			}
+			return true
+		})
+	}
+	walk(fn.Body, "T")
+	sort.SliceStable(events, func(a, b int) bool {
+		return events[a].Pos < events[b].Pos
+	})
+
+	emit := func(e *diagramEvent) {
+		e.done = true
+		switch {
+		case e.Label != "":
+			d.Steps = append(d.Steps, diagramStep{From: e.Participant, To: e.Participant, Text: e.Label, Note: true})
+		case e.Receive:
+			for _, send := range events {
+				// A participant may receive what it sent earlier on a
+				// buffered channel
+				if send.Send && !send.matched && send.Channel == e.Channel && (send.Participant != e.Participant || send.done) {
+					d.Steps = append(d.Steps, diagramStep{From: send.Participant, To: e.Participant, Text: send.Text})
+					send.matched = true
+					return
+				}
+			}
+			d.Steps = append(d.Steps, diagramStep{From: e.Participant, To: e.Participant, Text: e.Text, Note: true})
+		}
+	}
+	ordered := orderedLabels(events, order)
+	lastLabels := map[string]*diagramEvent{}
+	for _, label := range ordered {
+		lastLabels[label.Participant] = label
+	}
+	for _, label := range ordered {
+		if label.done {
+			continue
+		}
+		// Whatever the participant did since its previous label
+		// happens before this one
+		for _, e := range events {
+			if e == label {
+				break
+			}
+			if e.Participant == label.Participant && !e.done && e.Label == "" {
+				emit(e)
+			}
+		}
+		emit(label)
+		if lastLabels[label.Participant] != label {
+			continue
+		}
+		// and whatever it does after its last label right after it
+		for _, e := range events {
+			if e.Participant == label.Participant && !e.done && e.Label == "" && e.Pos > label.Pos {
+				emit(e)
+			}
+		}
+	}
+	for _, e := range events {
+		// Labels missing from the expected order are never reached
+		if !e.done && (e.Label == "" || len(ordered) == 0) {
+			emit(e)
+		}
+	}
+	// Sends that no receive in the function matches
+	for _, e := range events {
+		if e.Send && !e.matched {
+			d.Steps = append(d.Steps, diagramStep{From: e.Participant, To: e.Participant, Text: e.Text, Note: true})
+		}
+	}
+	return d, nil
+}
+
+// orderedLabels returns the label events in the order spelled by order,
+// or none if order cannot be spelled with them.
+func orderedLabels(events []*diagramEvent, order string) []*diagramEvent {
+	ordered := []*diagramEvent{}
+	used := map[*diagramEvent]bool{}
+	for rest := order; rest != ""; {
+		var next *diagramEvent
+		for _, e := range events {
+			if e.Label != "" && !used[e] && strings.HasPrefix(rest, e.Label) && (next == nil || len(e.Label) > len(next.Label)) {
+				next = e
+			}
+		}
+		if next == nil {
+			return nil
+		}
+		used[next] = true
+		ordered = append(ordered, next)
+		rest = rest[len(next.Label):]
+	}
+	return ordered
+}
+
+// Layout of diagrams drawn as SVG, in pixels
+const (
+	diagramColumn = 160
+	diagramRow    = 36
+	diagramMargin = 10
+	diagramBox    = 30
+)
+
+// diagramCount keeps the arrow markers of the diagrams on a page apart.
+var diagramCount = 0
+
+// diagramSVG draws the mermaid source of a diagram block as SVG.
+func diagramSVG(lines []string) string {
+	d := &diagram{}
+	for _, line := range lines {
+		if err := d.parse(line); err != nil {
+			return ""
+		}
+	}
+	diagramCount++
+	marker := fmt.Sprintf("diagram-arrow-%d", diagramCount)
+	columns := map[string]int{}
+	for i, p := range d.Participants {
+		columns[p.ID] = diagramMargin + i*diagramColumn + diagramColumn/2
+	}
+	width := 2*diagramMargin + len(d.Participants)*diagramColumn
+	top := diagramMargin + diagramBox
+	height := top + (len(d.Steps)+1)*diagramRow
+	svg := fmt.Sprintf("<svg xmlns=\"http://www.w3.org/2000/svg\" class=\"diagram\" width=\"%d\" height=\"%d\" viewBox=\"0 0 %d %d\" font-family=\"sans-serif\" font-size=\"12\">\n", width, height, width, height)
+	svg = svg + fmt.Sprintf("<defs><marker id=\"%s\" markerWidth=\"10\" markerHeight=\"7\" refX=\"10\" refY=\"3.5\" orient=\"auto\"><polygon points=\"0 0, 10 3.5, 0 7\"/></marker></defs>\n", marker)
+	for _, p := range d.Participants {
+		x := columns[p.ID]
+		svg = svg + fmt.Sprintf("<rect x=\"%d\" y=\"%d\" width=\"%d\" height=\"%d\" fill=\"#eef\" stroke=\"#666\"/>\n", x-diagramColumn/2+10, diagramMargin, diagramColumn-20, diagramBox)
+		svg = svg + fmt.Sprintf("<text x=\"%d\" y=\"%d\" text-anchor=\"middle\">%s</text>\n", x, diagramMargin+diagramBox/2+4, html.EscapeString(p.Name))
+		svg = svg + fmt.Sprintf("<line x1=\"%d\" y1=\"%d\" x2=\"%d\" y2=\"%d\" stroke=\"#999\" stroke-dasharray=\"4 4\"/>\n", x, top, x, height)
+	}
+	for i, s := range d.Steps {
+		y := top + (i+1)*diagramRow
+		from, to := columns[s.From], columns[s.To]
+		if s.Note {
+			left, right := min(from, to)-diagramColumn/2+20, max(from, to)+diagramColumn/2-20
+			svg = svg + fmt.Sprintf("<rect x=\"%d\" y=\"%d\" width=\"%d\" height=\"24\" fill=\"#ffc\" stroke=\"#aa3\"/>\n", left, y-17, right-left)
+			svg = svg + fmt.Sprintf("<text x=\"%d\" y=\"%d\" text-anchor=\"middle\">%s</text>\n", (left+right)/2, y, html.EscapeString(s.Text))
+			continue
+		}
+		dash := ""
+		if s.Dashed {
+			dash = " stroke-dasharray=\"6 3\""
+		}
+		if from == to {
+			svg = svg + fmt.Sprintf("<path d=\"M %d %d h 30 v 12 h -30\" fill=\"none\" stroke=\"#000\"%s marker-end=\"url(#%s)\"/>\n", from, y-6, dash, marker)
+			svg = svg + fmt.Sprintf("<text x=\"%d\" y=\"%d\">%s</text>\n", from+36, y+2, html.EscapeString(s.Text))
+			continue
+		}
+		svg = svg + fmt.Sprintf("<line x1=\"%d\" y1=\"%d\" x2=\"%d\" y2=\"%d\" stroke=\"#000\"%s marker-end=\"url(#%s)\"/>\n", from, y, to, y, dash, marker)
+		svg = svg + fmt.Sprintf("<text x=\"%d\" y=\"%d\" text-anchor=\"middle\">%s</text>\n", (from+to)/2, y-5, html.EscapeString(s.Text))
+	}
+	return svg + "</svg>\n"
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_DiagramMermaid(t *testing.T) {
+	statements := []string{
+		"participant T as Test",
+		"participant W as worker",
+		"T->>W: job",
+		"Note over W: works",
+		"W-->>T: done",
+		"Note over T,W: finished",
+	}
+	d := &diagram{}
+	for _, statement := range statements {
+		if err := d.parse(statement); err != nil {
+			t.Fatalf("parse(%q): %v", statement, err)
+		}
+	}
+	want := "sequenceDiagram\n    " + strings.Join(statements, "\n    ")
+	if got := strings.Join(d.mermaid(), "\n"); got != want {
+		t.Errorf("mermaid() =\n%s\nwant\n%s", got, want)
+	}
+	if err := d.parse("T => W"); err == nil {
+		t.Errorf("parse(%q) succeeded", "T => W")
+	}
+}
+
+func Test_DeriveDiagram(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "chapter_test.go")
+	source := `package chapter
+
+func Test_Order(t *testing.T) {
+	c := make(chan string)
+
+	execOrder := "A"
+
+	go func() {
+		execOrder = execOrder + "B"
+		c <- "message"
+		execOrder = execOrder + "E"
+		<-c
+		execOrder = execOrder + "Never"
+	}()
+
+	time.Sleep(time.Second)
+
+	execOrder = execOrder + "C"
+	message := <-c
+	execOrder = execOrder + "D"
+
+	time.Sleep(time.Second)
+
+	assert.Equal(t, "message", message)
+	assert.Equal(t, "ABCDE", execOrder)
+}
+`
+	if err := os.WriteFile(fileName, []byte(source), 0644); err != nil {
+		t.Fatal(err)
+	}
+	d, err := deriveDiagram(fileName, "Test_Order")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"sequenceDiagram",
+		"    participant T as Test_Order",
+		"    participant G1 as goroutine 1",
+		"    Note over T: A",
+		"    Note over G1: B",
+		"    Note over T: C",
+		`    G1->>T: c <- "message"`,
+		"    Note over T: D",
+		"    Note over G1: E",
+		"    Note over G1: <-c",
+	}
+	if got := strings.Join(d.mermaid(), "\n"); got != strings.Join(want, "\n") {
+		t.Errorf("deriveDiagram gave\n%s\nwant\n%s", got, strings.Join(want, "\n"))
+	}
+}
+
+func Test_DeriveDiagramBlockedGoroutine(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "chapter_test.go")
+	source := `package chapter
+
+func Test_Timer(t *testing.T) {
+	execOrder := "A"
+
+	timer := time.NewTimer(5 * time.Second)
+
+	futureFunc := func() {
+		execOrder = execOrder + "B"
+		<-timer.C
+		execOrder = execOrder + "Never"
+	}
+
+	go futureFunc()
+	time.Sleep(2 * time.Second)
+
+	execOrder = execOrder + "C"
+
+	timer.Stop()
+
+	execOrder = execOrder + "D"
+
+	assert.Equal(t, "ABCD", execOrder)
+}
+`
+	if err := os.WriteFile(fileName, []byte(source), 0644); err != nil {
+		t.Fatal(err)
+	}
+	d, err := deriveDiagram(fileName, "Test_Timer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The goroutine blocks on the timer right after B
+	want := []string{
+		"sequenceDiagram",
+		"    participant T as Test_Timer",
+		"    participant G1 as futureFunc",
+		"    Note over T: A",
+		"    Note over G1: B",
+		"    Note over G1: <-timer.C",
+		"    Note over T: C",
+		"    Note over T: D",
+	}
+	if got := strings.Join(d.mermaid(), "\n"); got != strings.Join(want, "\n") {
+		t.Errorf("deriveDiagram gave\n%s\nwant\n%s", got, strings.Join(want, "\n"))
+	}
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+)
+
+// A blockKind tells what a block holds in its Lines.
+type blockKind int
+
+const (
+	// headingBlock holds the title of a heading
+	headingBlock blockKind = iota
+	// proseBlock holds markdown lines
+	proseBlock
+	// codeBlock holds the lines of a Go snippet
+	codeBlock
+	// outputBlock holds captured output
+	outputBlock
+	// sourceBlock holds nothing; its File and Line are where the
+	// snippet's source link points to
+	sourceBlock
+	// badgeBlock holds the text of a badge, such as the minimum Go
+	// version of a section, with what requires it as its label
+	badgeBlock
+	// admonitionBlock holds markdown lines, with the kind of admonition,
+	// such as Warning, as its label
+	admonitionBlock
+	// calloutBlock holds the plain text of the callouts of a snippet,
+	// each after its marker
+	calloutBlock
+	// diagramBlock holds the mermaid source of a diagram
+	diagramBlock
+	// benchmarkBlock holds the rows of a table of benchmark results,
+	// cells separated by tabs, with the platform they ran on as its label
+	benchmarkBlock
+)
+
+// A block is a part of a document that each output format renders in its
+// own way.
+type block struct {
+	Kind blockKind
+	// Level is the level of a heading
+	Level int
+	Lines []string
+	// Label is the caption of a snippet, the label of output, or as given
+	// by the kind of block
+	Label string
+	// File and Line locate where a source link points to, and Line the
+	// line of a heading read from a chapter file
+	File string
+	Line int
+}
+
+// A document is what file2doc turns a chapter file into: a sequence of
+// blocks.
+type document struct {
+	File   string
+	Blocks []block
+}
+
+// add appends line to the last block if it is a block of the given kind,
+// or else starts a new one.
+func (doc *document) add(kind blockKind, line string) {
+	if last := len(doc.Blocks) - 1; last >= 0 && doc.Blocks[last].Kind == kind {
+		doc.Blocks[last].Lines = append(doc.Blocks[last].Lines, line)
+		return
+	}
+	doc.Blocks = append(doc.Blocks, block{Kind: kind, Lines: []string{line}})
+}
+
+// splitChapters regroups the blocks of documents into one document per
+// chapter, each starting at a level one heading. Blocks before the first
+// heading of a document belong to the previous chapter.
+func splitChapters(docs []*document) []*document {
+	chapters := []*document{}
+	var chapter *document
+	for _, doc := range docs {
+		for _, b := range doc.Blocks {
+			if chapter == nil || (b.Kind == headingBlock && b.Level == 1) {
+				chapter = &document{File: doc.File}
+				chapters = append(chapters, chapter)
+			}
+			chapter.Blocks = append(chapter.Blocks, b)
+		}
+	}
+	return chapters
+}
+
+// A renderer renders a document on its own, so that the output for a
+// number of files is the concatenation of theirs.
+type renderer func(doc *document) string
+
+var renderers = map[string]renderer{
+	"markdown": renderMarkdown,
+	"asciidoc": renderAsciidoc,
+	"rst":      renderRst,
+}
+
+// headingID returns the identifier pandoc gives to a heading, so that
+// links such as [Control Flow](#control-flow) work in every format.
+func headingID(title string) string {
+	id := ""
+	for _, r := range strings.ToLower(title) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '-' || r == '.':
+			id = id + string(r)
+		case unicode.IsSpace(r):
+			id = id + "-"
+		}
+	}
+	id = strings.TrimLeft(id, "0123456789-_.")
+	if id == "" {
+		return "section"
+	}
+	return id
+}
+
+// Prose is made of paragraphs, bullet list items and fenced blocks.
+type prosePartKind int
+
+const (
+	paragraphPart prosePartKind = iota
+	itemPart
+	fencePart
+)
+
+type prosePart struct {
+	Kind  prosePartKind
+	Lines []string
+}
+
+// splitProse splits the markdown lines of a prose block into parts. An
+// item continues over the following lines until a blank line or another
+// item.
+func splitProse(lines []string) []prosePart {
+	parts := []prosePart{}
+	var current *prosePart
+	insideFence := false
+	for _, line := range lines {
+		switch {
+		case strings.HasPrefix(line, "```"):
+			if insideFence {
+				current = nil
+			} else {
+				parts = append(parts, prosePart{Kind: fencePart, Lines: []string{}})
+				current = &parts[len(parts)-1]
+			}
+			insideFence = !insideFence
+		case insideFence:
+			current.Lines = append(current.Lines, line)
+		case strings.TrimSpace(line) == "":
+			current = nil
+		case strings.HasPrefix(line, "* ") || strings.HasPrefix(line, "- "):
+			parts = append(parts, prosePart{Kind: itemPart, Lines: []string{line[2:]}})
+			current = &parts[len(parts)-1]
+		case current == nil:
+			parts = append(parts, prosePart{Kind: paragraphPart, Lines: []string{line}})
+			current = &parts[len(parts)-1]
+		case current.Kind == itemPart:
+			current.Lines = append(current.Lines, strings.TrimSpace(line))
+		default:
+			current.Lines = append(current.Lines, line)
+		}
+	}
+	return parts
+}
+
+// An inlineRenderer renders inline markdown for an output format. Emph,
+// Strong and Link receive their text already rendered.
+type inlineRenderer struct {
+	Code   func(code string) string
+	Emph   func(text string) string
+	Strong func(text string) string
+	Link   func(label string, target string) string
+	Text   func(text string) string
+}
+
+// renderInline converts inline markdown (code spans, emphasis, strong
+// emphasis and links) with render, passing everything else to its Text.
+func renderInline(text string, render inlineRenderer) string {
+	rendered := ""
+	plain := ""
+	flush := func() {
+		if plain != "" {
+			rendered = rendered + render.Text(plain)
+			plain = ""
+		}
+	}
+	for i := 0; i < len(text); {
+		rest := text[i:]
+		if rest[0] == '`' {
+			if end := strings.Index(rest[1:], "`"); end >= 0 {
+				flush()
+				rendered = rendered + render.Code(rest[1:end+1])
+				i += end + 2
+				continue
+			}
+		}
+		if strings.HasPrefix(rest, "**") {
+			if end := strings.Index(rest[2:], "**"); end > 0 {
+				flush()
+				rendered = rendered + render.Strong(renderInline(rest[2:end+2], render))
+				i += end + 4
+				continue
+			}
+		}
+		if rest[0] == '_' && (i == 0 || !isWordByte(text[i-1])) {
+			if end := emphasisEnd(rest); end > 0 {
+				flush()
+				rendered = rendered + render.Emph(renderInline(rest[1:end], render))
+				i += end + 1
+				continue
+			}
+		}
+		if rest[0] == '[' {
+			if label, target, length, ok := markdownLink(rest); ok {
+				flush()
+				rendered = rendered + render.Link(renderInline(label, render), target)
+				i += length
+				continue
+			}
+		}
+		plain = plain + rest[:1]
+		i++
+	}
+	flush()
+	return rendered
+}
+
+func isWordByte(b byte) bool {
+	return b == '_' || (b >= '0' && b <= '9') || (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z')
+}
+
+// emphasisEnd returns the index of the underscore closing the emphasis
+// opened at the start of text, or -1.
+func emphasisEnd(text string) int {
+	for i := 2; i < len(text); i++ {
+		if text[i] == '_' && (i == len(text)-1 || !isWordByte(text[i+1])) {
+			return i
+		}
+	}
+	return -1
+}
+
+// markdownLink parses a [label](target) link at the start of text.
+func markdownLink(text string) (string, string, int, bool) {
+	closing := strings.Index(text, "](")
+	if closing < 0 {
+		return "", "", 0, false
+	}
+	end := strings.Index(text[closing:], ")")
+	if end < 0 {
+		return "", "", 0, false
+	}
+	return text[1:closing], text[closing+2 : closing+end], closing + end + 1, true
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"archive/zip"
+	"crypto/sha1"
+	"fmt"
+	"hash/crc32"
+	"html"
+	"io"
+	"mime"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// The EPUB output is an EPUB 3 archive with one XHTML page per chapter,
+// chapters starting at each level one heading. The book's metadata, its
+// introduction and its cover image come from the header file given with
+// -header, when there is one.
+
+const epubContainer = `<?xml version="1.0" encoding="UTF-8"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+  <rootfiles>
+    <rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/>
+  </rootfiles>
+</container>
+`
+
+const epubStyle = `body { font-family: serif; line-height: 1.4; }
+h1, h2, h3, h4 { font-family: sans-serif; }
+pre { font-family: monospace; font-size: 0.85em; white-space: pre-wrap; }
+pre.go { background: #f4f4f4; padding: 0.5em; }
+pre.output { border-left: 3px solid #ccc; padding-left: 0.5em; }
+p.source { font-size: 0.8em; }
+p.go-version { display: inline-block; font-size: 0.8em; border: 1px solid #888; border-radius: 0.3em; padding: 0 0.3em; }
+div.admonition { border-left: 4px solid; padding: 0 0.5em; margin: 1em 0; }
+div.note { border-color: #3a6ea5; background: #eef3f9; }
+div.warning { border-color: #b03a2e; background: #fbeeed; }
+div.tip { border-color: #2e8b57; background: #edf7f1; }
+p.admonition-title { font-family: sans-serif; font-weight: bold; margin: 0.3em 0; }
+ul.callouts { list-style: none; padding-left: 0.5em; font-size: 0.9em; }
+table.benchmarks { border-collapse: collapse; font-size: 0.85em; margin: 1em 0; }
+table.benchmarks caption { caption-side: bottom; font-size: 0.9em; }
+table.benchmarks th, table.benchmarks td { border-bottom: 1px solid #ccc; padding: 0.2em 0.5em; text-align: right; }
+table.benchmarks th:first-child, table.benchmarks td:first-child { text-align: left; }
+img.cover { max-width: 100%; }
+`
+
+const epubPageStart = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE html>
+<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops" xml:lang="en" lang="en">
+<head>
+<meta charset="utf-8"/>
+<title>%s</title>
+<link rel="stylesheet" type="text/css" href="style.css"/>
+</head>
+<body>
+`
+
+const epubPageEnd = `</body>
+</html>
+`
+
+type epubPage struct {
+	File  string
+	Title string
+	htmlPage
+}
+
+// renderEpub writes documents as an EPUB archive, with the metadata of
+// the header h, which may be nil.
+func renderEpub(w io.Writer, docs []*document, h *header, headerFile string) error {
+	if h == nil {
+		h = &header{Fields: map[string]string{}}
+	}
+	title := h.Fields["title"]
+	if title == "" {
+		title = "Untitled"
+	}
+
+	pages := []*epubPage{}
+	if strings.TrimSpace(h.Body) != "" {
+		pages = append(pages, &epubPage{File: "intro.xhtml", htmlPage: htmlPage{Blocks: markdown2doc(headerFile, h.Body).Blocks}})
+	}
+	for i, chapter := range splitChapters(docs) {
+		pages = append(pages, &epubPage{File: fmt.Sprintf("chapter%02d.xhtml", i+1), htmlPage: htmlPage{Blocks: chapter.Blocks}})
+	}
+	headingPages := map[string]string{}
+	for _, page := range pages {
+		page.IDs = headingIDs(page.Blocks, map[string]int{})
+		page.Pages = headingPages
+		for i, b := range page.Blocks {
+			if b.Kind != headingBlock {
+				continue
+			}
+			if page.Title == "" {
+				page.Title = b.Lines[0]
+			}
+			if _, found := headingPages[page.IDs[i]]; !found {
+				headingPages[page.IDs[i]] = page.File
+			}
+		}
+		if page.Title == "" {
+			page.Title = title
+		}
+	}
+
+	archive := zip.NewWriter(w)
+	// The mimetype must come first, stored uncompressed and without a
+	// data descriptor, so its header is written raw
+	mimetype := []byte("application/epub+zip")
+	entry, err := archive.CreateRaw(&zip.FileHeader{
+		Name:               "mimetype",
+		Method:             zip.Store,
+		CRC32:              crc32.ChecksumIEEE(mimetype),
+		CompressedSize64:   uint64(len(mimetype)),
+		UncompressedSize64: uint64(len(mimetype)),
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := entry.Write(mimetype); err != nil {
+		return err
+	}
+	add := func(name string, content []byte) error {
+		entry, err := archive.Create(name)
+		if err != nil {
+			return err
+		}
+		_, err = entry.Write(content)
+		return err
+	}
+
+	manifest := "    <item id=\"nav\" href=\"nav.xhtml\" media-type=\"application/xhtml+xml\" properties=\"nav\"/>\n"
+	manifest = manifest + "    <item id=\"style\" href=\"style.css\" media-type=\"text/css\"/>\n"
+	spine := ""
+	if image := h.Fields["image"]; image != "" {
+		imageFile := filepath.Join(filepath.Dir(headerFile), image)
+		data, err := os.ReadFile(imageFile)
+		if err != nil {
+			os.Stderr.WriteString(fmt.Sprintf("Skipping the cover image\n%v\n", err))
+		} else {
+			_, justFile := filepath.Split(imageFile)
+			mediaType := mime.TypeByExtension(filepath.Ext(justFile))
+			if err := add("OEBPS/"+justFile, data); err != nil {
+				return err
+			}
+			cover := fmt.Sprintf(epubPageStart, html.EscapeString(title)) +
+				"<p><img class=\"cover\" src=\"" + html.EscapeString(justFile) + "\" alt=\"" + html.EscapeString(title) + "\"/></p>\n" + epubPageEnd
+			if err := add("OEBPS/cover.xhtml", []byte(cover)); err != nil {
+				return err
+			}
+			manifest = manifest + fmt.Sprintf("    <item id=\"cover-image\" href=\"%s\" media-type=\"%s\" properties=\"cover-image\"/>\n", html.EscapeString(justFile), mediaType)
+			manifest = manifest + "    <item id=\"cover\" href=\"cover.xhtml\" media-type=\"application/xhtml+xml\"/>\n"
+			spine = spine + "    <itemref idref=\"cover\"/>\n"
+		}
+	}
+	for i, page := range pages {
+		content := fmt.Sprintf(epubPageStart, html.EscapeString(page.Title)) + htmlBody(page.htmlPage) + epubPageEnd
+		if err := add("OEBPS/"+page.File, []byte(content)); err != nil {
+			return err
+		}
+		manifest = manifest + fmt.Sprintf("    <item id=\"page%d\" href=\"%s\" media-type=\"application/xhtml+xml\"/>\n", i, page.File)
+		spine = spine + fmt.Sprintf("    <itemref idref=\"page%d\"/>\n", i)
+	}
+
+	if err := add("META-INF/container.xml", []byte(epubContainer)); err != nil {
+		return err
+	}
+	if err := add("OEBPS/style.css", []byte(epubStyle)); err != nil {
+		return err
+	}
+	if err := add("OEBPS/nav.xhtml", []byte(epubNav(title, pages))); err != nil {
+		return err
+	}
+	if err := add("OEBPS/content.opf", []byte(epubPackage(title, h, manifest, spine))); err != nil {
+		return err
+	}
+	return archive.Close()
+}
+
+// epubPackage returns the package document. The identifier is derived
+// from the title and author so that it is stable across builds.
+func epubPackage(title string, h *header, manifest string, spine string) string {
+	sum := sha1.Sum([]byte(title + "\n" + h.Fields["author"]))
+	uuid := fmt.Sprintf("%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16])
+	modified := "2000-01-01T00:00:00Z"
+	if date := h.Fields["date"]; len(date) == len("2006-01-02") {
+		modified = date + "T00:00:00Z"
+	}
+	metadata := "    <dc:identifier id=\"book-id\">urn:uuid:" + uuid + "</dc:identifier>\n"
+	metadata = metadata + "    <dc:title>" + html.EscapeString(title) + "</dc:title>\n"
+	metadata = metadata + "    <dc:language>en</dc:language>\n"
+	if author := h.Fields["author"]; author != "" {
+		metadata = metadata + "    <dc:creator>" + html.EscapeString(author) + "</dc:creator>\n"
+	}
+	if date := h.Fields["date"]; date != "" {
+		metadata = metadata + "    <dc:date>" + html.EscapeString(date) + "</dc:date>\n"
+	}
+	if abstract := h.Fields["abstract"]; abstract != "" {
+		metadata = metadata + "    <dc:description>" + html.EscapeString(abstract) + "</dc:description>\n"
+	}
+	metadata = metadata + "    <meta property=\"dcterms:modified\">" + modified + "</meta>\n"
+	return `<?xml version="1.0" encoding="UTF-8"?>
+<package xmlns="http://www.idpf.org/2007/opf" version="3.0" unique-identifier="book-id" xml:lang="en">
+  <metadata xmlns:dc="http://purl.org/dc/elements/1.1/">
+` + metadata + `  </metadata>
+  <manifest>
+` + manifest + `  </manifest>
+  <spine>
+` + spine + `  </spine>
+</package>
+`
+}
+
+// epubNav returns the navigation document, listing the level one and two
+// headings of every page.
+func epubNav(title string, pages []*epubPage) string {
+	nav := fmt.Sprintf(epubPageStart, html.EscapeString(title))
+	nav = nav + "<nav epub:type=\"toc\" id=\"toc\">\n<h1>Contents</h1>\n<ol>\n"
+	openChapter, openSections := false, false
+	for _, page := range pages {
+		for i, b := range page.Blocks {
+			if b.Kind != headingBlock || b.Level > 2 {
+				continue
+			}
+			link := "<a href=\"" + page.File + "#" + page.IDs[i] + "\">" + page.inline(b.Lines[0]) + "</a>"
+			if b.Level == 2 && openChapter {
+				if !openSections {
+					nav = nav + "<ol>\n"
+					openSections = true
+				}
+				nav = nav + "<li>" + link + "</li>\n"
+				continue
+			}
+			if openSections {
+				nav = nav + "</ol>\n"
+				openSections = false
+			}
+			if openChapter {
+				nav = nav + "</li>\n"
+			}
+			nav = nav + "<li>" + link + "\n"
+			openChapter = true
+		}
+	}
+	if openSections {
+		nav = nav + "</ol>\n"
+	}
+	if openChapter {
+		nav = nav + "</li>\n"
+	}
+	return nav + "</ol>\n</nav>\n" + epubPageEnd
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+func Test_EpubMimetype(t *testing.T) {
+	var epub bytes.Buffer
+	if err := renderEpub(&epub, []*document{goldenDocument()}, nil, ""); err != nil {
+		t.Fatal(err)
+	}
+	data := epub.Bytes()
+	// A bare stored entry: local file header, no data descriptor, no
+	// extra field, then the content
+	if binary.LittleEndian.Uint32(data) != 0x04034b50 {
+		t.Fatalf("EPUB does not start with a local file header")
+	}
+	if flags := binary.LittleEndian.Uint16(data[6:]); flags != 0 {
+		t.Errorf("mimetype flags = %#x; want 0", flags)
+	}
+	if method := binary.LittleEndian.Uint16(data[8:]); method != 0 {
+		t.Errorf("mimetype method = %d; want stored", method)
+	}
+	if extra := binary.LittleEndian.Uint16(data[28:]); extra != 0 {
+		t.Errorf("mimetype extra field length = %d; want 0", extra)
+	}
+	if content := string(data[30:58]); content != "mimetypeapplication/epub+zip" {
+		t.Errorf("EPUB starts with %q; want %q", content, "mimetypeapplication/epub+zip")
+	}
+}
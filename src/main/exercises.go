@@ -0,0 +1,250 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Exercises are copies of the chapter packages in which the expected
+// side of every assertion whose expected value is a literal is replaced
+// by a placeholder of the same type that is sure to be wrong, so that no
+// exercise starts out solved. The placeholder compiles, so a trainee runs
+// `go test` to find out which answers are still missing.
+
+const exerciseMarker = "// TODO"
+const workbookBlank = "____"
+const defaultExercisesDir = "exercises"
+
+// Set while rendering the workbook so that file2md blanks out answers.
+var blankAnswers = false
+
+func exercisesCommand(args []string) int {
+	outDir := defaultExercisesDir
+	exerciseFiles := []string{}
+	skip := false
+	for index, arg := range args {
+		if arg == "-o" {
+			if index >= len(args)-1 {
+				fmt.Printf("No arguments after -o\n")
+				return 1
+			}
+			outDir = args[index+1]
+			skip = true
+		} else if !skip {
+			exerciseFiles = append(exerciseFiles, arg)
+		} else {
+			skip = false
+		}
+	}
+	if len(exerciseFiles) == 0 {
+		fmt.Printf("Usage:\n\n")
+		fmt.Printf("    go2md exercises [-o <DIR>] <file_1> <file_2> ... <file_n>\n\n")
+		return 0
+	}
+
+	copiedDirs := map[string]bool{}
+	for _, fileName := range exerciseFiles {
+		dir, _ := filepath.Split(fileName)
+		if !copiedDirs[dir] {
+			if err := copyDir(dir, filepath.Join(outDir, dir)); err != nil {
+				fmt.Printf("Unable to copy %s\n%v\n", dir, err)
+				return 1
+			}
+			copiedDirs[dir] = true
+		}
+		data, err := os.ReadFile(fileName)
+		if err != nil {
+			fmt.Printf("Unable to read file %s\n%v\n", fileName, err)
+			return 1
+		}
+		lines := strings.Split(string(data), "\n")
+		count := 0
+		for i, line := range lines {
+			if exercise, found := exerciseLine(line); found {
+				lines[i] = exercise
+				count++
+			}
+		}
+		target := filepath.Join(outDir, fileName)
+		if err := os.WriteFile(target, []byte(strings.Join(lines, "\n")), 0644); err != nil {
+			fmt.Printf("Unable to write file %s\n%v\n", target, err)
+			return 1
+		}
+		fmt.Printf("%s: %d exercises\n", target, count)
+	}
+
+	blankAnswers = true
+	workbook := ""
+	for _, fileName := range exerciseFiles {
+		workbook = workbook + file2md(fileName)
+	}
+	workbookFile := filepath.Join(outDir, "workbook.md")
+	if err := os.WriteFile(workbookFile, []byte(workbook), 0644); err != nil {
+		fmt.Printf("Unable to write file %s\n%v\n", workbookFile, err)
+		return 1
+	}
+	fmt.Printf("%s\n", workbookFile)
+	return 0
+}
+
+// copyDir copies the regular files in src to dst, creating dst if needed.
+func copyDir(src string, dst string) error {
+	if err := os.MkdirAll(dst, 0755); err != nil {
+		return err
+	}
+	entries, err := os.ReadDir(filepath.Clean(src))
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(src, entry.Name()))
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(dst, entry.Name()), data, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// exerciseLine replaces the expected operand of the assertion in line by
+// its placeholder and marks the line with a TODO comment. Assertions
+// without a placeholder that is sure to be wrong are no exercise, and
+// neither is assert.NotEqual, which any placeholder but the answer
+// satisfies.
+func exerciseLine(line string) (string, bool) {
+	if strings.HasPrefix(strings.TrimLeft(line, " \t"), "assert.NotEqual(") {
+		return line, false
+	}
+	expected, start, end, found := expectedOperand(line)
+	if !found {
+		return line, false
+	}
+	value, found := placeholder(expected, line[start:end])
+	if !found {
+		return line, false
+	}
+	return line[:start] + value + line[end:] + " " + exerciseMarker, true
+}
+
+// workbookLine replaces the expected operand of the assertion in line by
+// a blank to be filled in on paper.
+func workbookLine(line string) string {
+	_, start, end, found := expectedOperand(line)
+	if !found {
+		return line
+	}
+	return line[:start] + workbookBlank + line[end:]
+}
+
+// expectedOperand locates the expected operand of an assert.Equal or
+// assert.NotEqual call in line, provided that it is a literal.
+func expectedOperand(line string) (ast.Expr, int, int, bool) {
+	trimmed := strings.TrimLeft(line, " \t")
+	offset := len(line) - len(trimmed)
+	if !strings.HasPrefix(trimmed, "assert.Equal(t, ") && !strings.HasPrefix(trimmed, "assert.NotEqual(t, ") {
+		return nil, 0, 0, false
+	}
+	expr, err := parser.ParseExpr(trimmed)
+	if err != nil {
+		return nil, 0, 0, false
+	}
+	call, ok := expr.(*ast.CallExpr)
+	if !ok || len(call.Args) < 3 || !isLiteral(call.Args[1]) {
+		return nil, 0, 0, false
+	}
+	// Positions of a parsed expression are 1-based offsets
+	start := offset + int(call.Args[1].Pos()) - 1
+	end := offset + int(call.Args[1].End()) - 1
+	return call.Args[1], start, end, true
+}
+
+// placeholders gives the zero value and a non-zero value of each kind of
+// basic literal.
+var placeholders = map[token.Token][2]string{
+	token.INT:    {"0", "1"},
+	token.FLOAT:  {"0.0", "1.0"},
+	token.IMAG:   {"0i", "1i"},
+	token.CHAR:   {"rune(0)", "rune(1)"},
+	token.STRING: {`""`, `"?"`},
+}
+
+// placeholder returns a value with the same type as the literal expr,
+// whose source is src, that differs from it: the zero value, or one when
+// expr is the zero value. Nil and composite literals that are empty or
+// hold zero values only, such as [3]int{0, 0, 0}, have none.
+func placeholder(expr ast.Expr, src string) (string, bool) {
+	switch e := expr.(type) {
+	case *ast.BasicLit:
+		values := placeholders[e.Kind]
+		if isZero(e) {
+			return values[1], true
+		}
+		return values[0], true
+	case *ast.Ident:
+		switch e.Name {
+		case "true":
+			return "false", true
+		case "false":
+			return "true", true
+		}
+	case *ast.UnaryExpr:
+		return placeholder(e.X, src)
+	case *ast.ParenExpr:
+		return placeholder(e.X, src)
+	case *ast.CompositeLit:
+		if !isZeroLiteral(e) {
+			// Keep the literal's type, dropping its elements
+			return src[:int(e.Lbrace-e.Pos())] + "{}", true
+		}
+	}
+	return "", false
+}
+
+// isZeroLiteral reports whether expr is a literal of zero values only,
+// which may equal the zero value of its type.
+func isZeroLiteral(expr ast.Expr) bool {
+	switch e := expr.(type) {
+	case *ast.BasicLit:
+		return isZero(e)
+	case *ast.Ident:
+		return e.Name == "false" || e.Name == "nil"
+	case *ast.UnaryExpr:
+		return isZeroLiteral(e.X)
+	case *ast.ParenExpr:
+		return isZeroLiteral(e.X)
+	case *ast.KeyValueExpr:
+		return isZeroLiteral(e.Value)
+	case *ast.CompositeLit:
+		for _, elt := range e.Elts {
+			if !isZeroLiteral(elt) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// isZero reports whether the basic literal lit is the zero value of its
+// type.
+func isZero(lit *ast.BasicLit) bool {
+	value := constant.MakeFromLiteral(lit.Value, lit.Kind, 0)
+	switch value.Kind() {
+	case constant.String:
+		return constant.StringVal(value) == ""
+	case constant.Int, constant.Float, constant.Complex:
+		return constant.Sign(value) == 0
+	}
+	return false
+}
@@ -0,0 +1,35 @@
+package main
+
+import "testing"
+
+func Test_ExerciseLine(t *testing.T) {
+	for _, c := range []struct {
+		line     string
+		exercise string
+		found    bool
+	}{
+		{"\tassert.Equal(t, 3, len(s))", "\tassert.Equal(t, 0, len(s)) // TODO", true},
+		{"\tassert.Equal(t, 0, len(s))", "\tassert.Equal(t, 1, len(s)) // TODO", true},
+		{"\tassert.Equal(t, 0x0, n)", "\tassert.Equal(t, 1, n) // TODO", true},
+		{"\tassert.Equal(t, -0.0, f)", "\tassert.Equal(t, 1.0, f) // TODO", true},
+		{`	assert.Equal(t, "", s)`, `	assert.Equal(t, "?", s) // TODO`, true},
+		{`	assert.Equal(t, "go", s)`, `	assert.Equal(t, "", s) // TODO`, true},
+		{"\tassert.Equal(t, '\\x00', r)", "\tassert.Equal(t, rune(1), r) // TODO", true},
+		{"\tassert.Equal(t, false, ok)", "\tassert.Equal(t, true, ok) // TODO", true},
+		{"\tassert.NotEqual(t, true, ok)", "\tassert.NotEqual(t, true, ok)", false},
+		{"\tassert.NotEqual(t, 1, b)", "\tassert.NotEqual(t, 1, b)", false},
+		{"\tassert.NotEqual(t, [2]string{}, array[0:2])", "\tassert.NotEqual(t, [2]string{}, array[0:2])", false},
+		{"\tassert.Equal(t, []int{1, 2}, s)", "\tassert.Equal(t, []int{}, s) // TODO", true},
+		{"\tassert.Equal(t, []int{}, s)", "\tassert.Equal(t, []int{}, s)", false},
+		{"\tassert.Equal(t, [3]int{0, 0, 0}, a)", "\tassert.Equal(t, [3]int{0, 0, 0}, a)", false},
+		{`	assert.Equal(t, point{X: 0, Y: -0.0, Name: ""}, p)`, `	assert.Equal(t, point{X: 0, Y: -0.0, Name: ""}, p)`, false},
+		{"\tassert.Equal(t, [3]int{0, 1, 0}, a)", "\tassert.Equal(t, [3]int{}, a) // TODO", true},
+		{"\tassert.Equal(t, nil, err)", "\tassert.Equal(t, nil, err)", false},
+		{"\tassert.Equal(t, n, m)", "\tassert.Equal(t, n, m)", false},
+	} {
+		exercise, found := exerciseLine(c.line)
+		if exercise != c.exercise || found != c.found {
+			t.Errorf("exerciseLine(%q) = %q, %v; want %q, %v", c.line, exercise, found, c.exercise, c.found)
+		}
+	}
+}
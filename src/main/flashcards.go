@@ -0,0 +1,153 @@
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"html"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Flashcards turn every assert.Equal into a question and answer pair. The
+// front shows the section, the test code leading up to the assertion and
+// the expression under test; the back shows the expected value. Cards
+// are written in Anki's TSV format with the chapter as a tag and a GUID
+// derived from the file, the test and the expression under test rather
+// than from the line number or the answer, so that re-importing updates
+// the existing cards.
+
+type flashcard struct {
+	GUID  string
+	Front string
+	Back  string
+	Tag   string
+}
+
+func flashcardsCommand(args []string) int {
+	outFile := ""
+	cardFiles := []string{}
+	skip := false
+	for index, arg := range args {
+		if arg == "-o" {
+			if index >= len(args)-1 {
+				fmt.Printf("No arguments after -o\n")
+				return 1
+			}
+			outFile = args[index+1]
+			skip = true
+		} else if !skip {
+			cardFiles = append(cardFiles, arg)
+		} else {
+			skip = false
+		}
+	}
+	if len(cardFiles) == 0 {
+		fmt.Printf("Usage:\n\n")
+		fmt.Printf("    go2md flashcards [-o <FILE>] <file_1> <file_2> ... <file_n>\n\n")
+		return 0
+	}
+
+	tsv := "#separator:tab\n#html:true\n#guid column:1\n#tags column:4\n"
+	chapter := ""
+	for _, fileName := range cardFiles {
+		cards, nextChapter, err := fileFlashcards(fileName, chapter)
+		if err != nil {
+			fmt.Printf("Unable to read file %s\n%v\n", fileName, err)
+			return 1
+		}
+		chapter = nextChapter
+		for _, card := range cards {
+			tsv = tsv + strings.Join([]string{card.GUID, card.Front, card.Back, card.Tag}, "\t") + "\n"
+		}
+	}
+	if outFile == "" {
+		os.Stdout.WriteString(tsv)
+		return 0
+	}
+	if err := os.WriteFile(outFile, []byte(tsv), 0644); err != nil {
+		fmt.Printf("Unable to write file %s\n%v\n", outFile, err)
+		return 1
+	}
+	return 0
+}
+
+// fileFlashcards returns a card for each assert.Equal inside the test
+// functions of fileName.
+func fileFlashcards(fileName string, chapter string) ([]flashcard, string, error) {
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, chapter, err
+	}
+	lineLocations, nextChapter, err := locations(fileName, chapter)
+	if err != nil {
+		return nil, chapter, err
+	}
+	_, justFile := filepath.Split(fileName)
+	cards := []flashcard{}
+	seen := map[string]int{}
+	testName := ""
+	body := []string{}
+	for i, line := range strings.Split(string(data), "\n") {
+		if strings.HasPrefix(line, "func Test_") {
+			testName = funcName(line)
+			body = []string{}
+			continue
+		}
+		if testName == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "}") {
+			testName = ""
+			continue
+		}
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "assert.Equal(t, ") {
+			expr, err := parser.ParseExpr(trimmed)
+			if call, ok := expr.(*ast.CallExpr); err == nil && ok && len(call.Args) >= 3 {
+				expected := trimmed[call.Args[1].Pos()-1 : call.Args[1].End()-1]
+				actual := trimmed[call.Args[2].Pos()-1 : call.Args[2].End()-1]
+				location := lineLocations[i+1]
+				identity := strings.Join([]string{justFile, testName, actual}, "\x00")
+				seen[identity]++
+				sum := sha1.Sum([]byte(fmt.Sprintf("%s\x00%d", identity, seen[identity])))
+				cards = append(cards, flashcard{
+					GUID:  "go2md-" + hex.EncodeToString(sum[:8]),
+					Front: flashcardFront(location, body, actual),
+					Back:  "<pre>" + flashcardHTML(expected) + "</pre>",
+					Tag:   strings.ReplaceAll(location.Chapter, " ", "_"),
+				})
+			}
+		}
+		if strings.Contains(line, "assert.") {
+			line = assert2equality(line)
+		}
+		body = append(body, line)
+	}
+	return cards, nextChapter, nil
+}
+
+func flashcardFront(location location, body []string, actual string) string {
+	lines := append([]string{}, body...)
+	all := map[int]bool{}
+	for i := range lines {
+		all[i] = true
+	}
+	dedent(lines, all)
+	code := strings.Trim(strings.Join(lines, "\n"), "\n")
+	front := "<b>" + html.EscapeString(location.Chapter) + ": " + html.EscapeString(location.Section) + "</b>"
+	if code != "" {
+		front = front + "<pre>" + flashcardHTML(code) + "</pre>"
+	}
+	return front + "<pre>" + symbols.equality(workbookBlank, flashcardHTML(actual), true) + "</pre>"
+}
+
+// flashcardHTML escapes code for an HTML field of a TSV line, which can
+// hold neither tabs nor line breaks.
+func flashcardHTML(code string) string {
+	code = html.EscapeString(strings.ReplaceAll(code, "\t", "    "))
+	return strings.ReplaceAll(code, "\n", "<br>")
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const flashcardsChapter = `package maps
+
+// # Maps
+// ## Lookups
+
+func Test_Lookup(t *testing.T) {
+	m := map[string]int{"a": 1}
+	assert.Equal(t, 1, m["a"])
+	assert.Equal(t, 2, len(m)) // one key
+	assert.Equal(t, 1, m["a"])
+}
+`
+
+func flashcardGUIDs(t *testing.T, source string) []string {
+	fileName := filepath.Join(t.TempDir(), "maps_test.go")
+	if err := os.WriteFile(fileName, []byte(source), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cards, _, err := fileFlashcards(fileName, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	guids := []string{}
+	for _, card := range cards {
+		guids = append(guids, card.GUID)
+	}
+	return guids
+}
+
+func Test_FlashcardGUIDs(t *testing.T) {
+	original := flashcardGUIDs(t, flashcardsChapter)
+	if len(original) != 3 || original[0] == original[2] {
+		t.Fatalf("GUIDs %v; want three distinct ones", original)
+	}
+	fixed := strings.Replace(flashcardsChapter, "assert.Equal(t, 2, len(m)) // one key", "assert.Equal(t, 1, len(m)) // a single key", 1)
+	if guids := flashcardGUIDs(t, fixed); strings.Join(guids, " ") != strings.Join(original, " ") {
+		t.Errorf("fixing an expected value changed the GUIDs from %v to %v", original, guids)
+	}
+	renamed := strings.Replace(flashcardsChapter, "len(m)", "len(m) + 0", 1)
+	if guids := flashcardGUIDs(t, renamed); guids[1] == original[1] {
+		t.Errorf("changing the expression under test kept GUID %s", guids[1])
+	}
+}
+
+func Test_FlashcardFrontDedent(t *testing.T) {
+	body := []string{"\tfor i := 0; i < 2; i++ {", "\t\tn++", "\t}"}
+	front := flashcardFront(location{Chapter: "Loops", Section: "For"}, body, "n")
+	if want := "<pre>for i := 0; i &lt; 2; i++ {<br>    n++<br>}</pre>"; !strings.Contains(front, want) {
+		t.Errorf("flashcardFront = %q; want it to contain %q", front, want)
+	}
+}
@@ -4,22 +4,72 @@ import (
 	"bufio"
 	"fmt"
 	"os"
-	"path/filepath"
+	"strconv"
 	"strings"
 )
 
 var srcRoot = ""
 var files = []string{}
+var compilerOutput = false
+var showValues = false
+var format = "markdown"
+var headerFile = ""
+var outputDir = defaultNotebooksDir
 
 func main() {
 	if len(os.Args) < 2 {
 		fmt.Printf("Go to Markdown (go2md) converts a .go file to markdown\n")
 		fmt.Printf("Usage:\n\n")
-		fmt.Printf("    go2md <file_1> <file_2> ... <file_n>\n\n")
+		fmt.Printf("    go2md <file_1> <file_2> ... <file_n>\n")
+		fmt.Printf("    go2md nocompile <file_1> <file_2> ... <file_n>\n")
+		fmt.Printf("    go2md refs <file_1> <file_2> ... <file_n>\n")
+		fmt.Printf("    go2md coverage [-n <TOP>] <file_1> <file_2> ... <file_n>\n")
+		fmt.Printf("    go2md lint [-json] <file_1> <file_2> ... <file_n>\n")
+		fmt.Printf("    go2md exercises [-o <DIR>] <file_1> <file_2> ... <file_n>\n")
+		fmt.Printf("    go2md grade [-o <DIR>] [-p <PROGRESS_FILE>] <file_1> <file_2> ... <file_n>\n")
+		fmt.Printf("    go2md flashcards [-o <FILE>] <file_1> <file_2> ... <file_n>\n")
+		fmt.Printf("    go2md tui [<MANIFEST>]\n")
+		fmt.Printf("    go2md site [-o <DIR>] [-m <MANIFEST>] [-header <HEADER_FILE>] [-for <hugo|jekyll>]\n")
+		fmt.Printf("    go2md playground [-addr <HOST:PORT>] [-m <MANIFEST>] [-cpu <SECONDS>] [-mem <MB>] [-timeout <SECONDS>]\n\n")
 		fmt.Printf("Optional Flags:\n\n")
-		fmt.Printf("    -r <SRC_ROOT>n")
+		fmt.Printf("    -r <SRC_ROOT>\n")
+		fmt.Printf("    -format <markdown|asciidoc|rst|latex|epub|ipynb>\n")
+		fmt.Printf("    -header <HEADER_FILE>    Book metadata and introduction for epub, or to open markdown\n")
+		fmt.Printf("    -o <DIR>    Directory to write ipynb notebooks to (default %s)\n", defaultNotebooksDir)
+		fmt.Printf("    -go <VERSION>    Exclude or flag sections requiring a newer Go release\n")
+		fmt.Printf("    -newer <exclude|flag>    What to do with such sections (default exclude)\n")
+		fmt.Printf("    -tabwidth <N>    Expand tabs in code to N columns\n")
+		fmt.Printf("    -tests <body|full|caption>    How to render tests (default body)\n")
+		fmt.Printf("    -symbols <unicode|ascii|comment>    How to render assertions (default unicode)\n")
+		fmt.Printf("    -callouts    Lift trailing comments out of code into numbered callouts\n")
+		fmt.Printf("    -strict    Fail if rendered code uses deprecated APIs\n")
+		fmt.Printf("    -bench    Run the benchmarks of each section and tabulate their results\n")
+		fmt.Printf("    -e    Render compiler errors of lines that won't compile\n")
+		fmt.Printf("    -v    Run the tests and show the values of non-literal assertion operands\n")
 		os.Exit(0)
 	}
+	switch os.Args[1] {
+	case "nocompile":
+		os.Exit(nocompileCommand(os.Args[2:]))
+	case "refs":
+		os.Exit(refsCommand(os.Args[2:]))
+	case "coverage":
+		os.Exit(coverageCommand(os.Args[2:]))
+	case "lint":
+		os.Exit(lintCommand(os.Args[2:]))
+	case "exercises":
+		os.Exit(exercisesCommand(os.Args[2:]))
+	case "grade":
+		os.Exit(gradeCommand(os.Args[2:]))
+	case "flashcards":
+		os.Exit(flashcardsCommand(os.Args[2:]))
+	case "tui":
+		os.Exit(tuiCommand(os.Args[2:]))
+	case "playground":
+		os.Exit(playgroundCommand(os.Args[2:]))
+	case "site":
+		os.Exit(siteCommand(os.Args[2:]))
+	}
 	skip := false
 	for index, arg := range os.Args[1:] {
 		if arg == "-r" {
@@ -29,6 +79,94 @@ func main() {
 			}
 			srcRoot = os.Args[index+2]
 			skip = true
+		} else if arg == "-format" {
+			if index >= len(os.Args)-2 {
+				fmt.Printf("No arguments after -format\n")
+				os.Exit(1)
+			}
+			format = os.Args[index+2]
+			skip = true
+		} else if arg == "-header" {
+			if index >= len(os.Args)-2 {
+				fmt.Printf("No arguments after -header\n")
+				os.Exit(1)
+			}
+			headerFile = os.Args[index+2]
+			skip = true
+		} else if arg == "-o" {
+			if index >= len(os.Args)-2 {
+				fmt.Printf("No arguments after -o\n")
+				os.Exit(1)
+			}
+			outputDir = os.Args[index+2]
+			skip = true
+		} else if arg == "-go" {
+			if index >= len(os.Args)-2 {
+				fmt.Printf("No arguments after -go\n")
+				os.Exit(1)
+			}
+			goVersion = os.Args[index+2]
+			if _, valid := parseGoVersion(goVersion); !valid {
+				fmt.Printf("Invalid value for -go: %s\n", goVersion)
+				os.Exit(1)
+			}
+			skip = true
+		} else if arg == "-newer" {
+			if index >= len(os.Args)-2 {
+				fmt.Printf("No arguments after -newer\n")
+				os.Exit(1)
+			}
+			newerSections = os.Args[index+2]
+			if newerSections != "exclude" && newerSections != "flag" {
+				fmt.Printf("Invalid value for -newer: %s\n", newerSections)
+				os.Exit(1)
+			}
+			skip = true
+		} else if arg == "-tabwidth" {
+			if index >= len(os.Args)-2 {
+				fmt.Printf("No arguments after -tabwidth\n")
+				os.Exit(1)
+			}
+			width, err := strconv.Atoi(os.Args[index+2])
+			if err != nil || width < 1 {
+				fmt.Printf("Invalid value for -tabwidth: %s\n", os.Args[index+2])
+				os.Exit(1)
+			}
+			tabWidth = width
+			skip = true
+		} else if arg == "-tests" {
+			if index >= len(os.Args)-2 {
+				fmt.Printf("No arguments after -tests\n")
+				os.Exit(1)
+			}
+			testWrappers = os.Args[index+2]
+			if !validTestWrappers(testWrappers) {
+				fmt.Printf("Invalid value for -tests: %s\n", testWrappers)
+				os.Exit(1)
+			}
+			skip = true
+		} else if arg == "-symbols" {
+			if index >= len(os.Args)-2 {
+				fmt.Printf("No arguments after -symbols\n")
+				os.Exit(1)
+			}
+			set, found := symbolSets[os.Args[index+2]]
+			if !found {
+				fmt.Printf("Invalid value for -symbols: %s\n", os.Args[index+2])
+				os.Exit(1)
+			}
+			symbols = set
+			skip = true
+		} else if arg == "-callouts" {
+			calloutMode = true
+		} else if arg == "-bench" {
+			benchmarkMode = true
+		} else if arg == "-strict" {
+			strictDeprecations = true
+		} else if arg == "-e" {
+			compilerOutput = true
+		} else if arg == "-v" {
+			showValues = true
 		} else if !skip {
 			files = append(files, arg)
 		} else {
@@ -36,13 +174,97 @@ func main() {
 		}
 	}
 
+	if format == "ipynb" {
+		if err := writeNotebooks(files, outputDir); err != nil {
+			fmt.Printf("Unable to write notebooks\n%v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	docs := []*document{}
+	deprecated := 0
 	for _, fileName := range files {
-		os.Stdout.WriteString(file2md(fileName))
+		doc := file2doc(fileName)
+		if err := applyVersions(doc); err != nil {
+			if goVersion != "" {
+				fmt.Printf("Unable to determine Go versions of file %s\n%v\n", fileName, err)
+				os.Exit(1)
+			}
+			// Badges are not worth failing the build over
+			os.Stderr.WriteString(fmt.Sprintf("%s: no Go version badges: %v\n", fileName, err))
+		}
+		uses, err := applyDeprecations(doc)
+		if err != nil {
+			fmt.Printf("Unable to find deprecated APIs in file %s\n%v\n", fileName, err)
+			os.Exit(1)
+		}
+		for _, use := range uses {
+			os.Stderr.WriteString(fmt.Sprintf("%s:%d: %s\n", fileName, use.Line, use.Text()))
+		}
+		deprecated = deprecated + len(uses)
+		if tabWidth > 0 {
+			applyTabWidth(doc, tabWidth)
+		}
+		if calloutMode {
+			applyCallouts(doc)
+		}
+		if benchmarkMode {
+			if err := applyBenchmarks(doc); err != nil {
+				fmt.Printf("Unable to run the benchmarks of file %s\n%v\n", fileName, err)
+				os.Exit(1)
+			}
+		}
+		docs = append(docs, doc)
+	}
+	if strictDeprecations && deprecated > 0 {
+		fmt.Printf("Rendered code uses deprecated APIs %d times\n", deprecated)
+		os.Exit(1)
+	}
+	switch format {
+	case "latex":
+		os.Stdout.WriteString(renderLatex(docs))
+	case "epub":
+		var h *header
+		if headerFile != "" {
+			var err error
+			h, err = readHeader(headerFile)
+			if err != nil {
+				fmt.Printf("Unable to read header %s\n%v\n", headerFile, err)
+				os.Exit(1)
+			}
+		}
+		if err := renderEpub(os.Stdout, docs, h, headerFile); err != nil {
+			fmt.Printf("Unable to write EPUB\n%v\n", err)
+			os.Exit(1)
+		}
+	default:
+		render, found := renderers[format]
+		if !found {
+			fmt.Printf("Unknown format %s\n", format)
+			os.Exit(1)
+		}
+		if format == "markdown" && headerFile != "" {
+			// The header opens the book as it is, but for its legend
+			data, err := os.ReadFile(headerFile)
+			if err != nil {
+				fmt.Printf("Unable to read header %s\n%v\n", headerFile, err)
+				os.Exit(1)
+			}
+			os.Stdout.WriteString(symbols.legend(string(data)))
+		}
+		for _, doc := range docs {
+			os.Stdout.WriteString(render(doc))
+		}
 	}
 
 }
 
 func file2md(fileName string) string {
+	return renderMarkdown(file2doc(fileName))
+}
+
+func file2doc(fileName string) *document {
 
 	os.Stderr.WriteString(fmt.Sprintf("%v\n", fileName))
 
@@ -54,11 +276,58 @@ func file2md(fileName string) string {
 	}
 	input := bufio.NewScanner(file)
 
-	mdString := ""
+	doc := &document{File: fileName}
 	insideCodeBlock := false
 	insideTestBlock := false
 	ignoring := false
 	lineCounter, lastSourceLine := 0, 0
+	compilerMessages := map[int]string{}
+	pendingMessages := []string{}
+	var pendingRun *runRequest
+	var pendingDiagram *diagramRequest
+	var values map[int]*assertValues
+	if showValues {
+		values = recordedValues(fileName)
+	}
+	if compilerOutput {
+		results, err := checkNoCompile(fileName)
+		if err != nil {
+			fmt.Printf("Unable to check file %s\n%v\n", fileName, err)
+			os.Exit(1)
+		}
+		for _, result := range results {
+			if result.Undefined {
+				os.Stderr.WriteString(fmt.Sprintf("%s:%d: not rendering an error on an undefined name: %s\n", fileName, result.Line, result.Message))
+			} else if !result.Compiles {
+				compilerMessages[result.Line] = result.Message
+			}
+		}
+	}
+	// Indices of the test body lines in the open code block
+	testLines := map[int]bool{}
+	wrappers, pendingCaption := testWrappers, ""
+	closeCodeBlock := func(sourceLine int) {
+		dedent(doc.Blocks[len(doc.Blocks)-1].Lines, testLines)
+		testLines = map[int]bool{}
+		if len(pendingMessages) > 0 {
+			doc.Blocks = append(doc.Blocks, block{Kind: outputBlock, Lines: pendingMessages})
+			pendingMessages = []string{}
+		}
+		if pendingRun != nil && pendingRun.Target != "" {
+			doc.Blocks = append(doc.Blocks, runBlocks(fileName, pendingRun)...)
+			pendingRun = nil
+		}
+		if pendingDiagram != nil && (pendingDiagram.Target != "" || len(pendingDiagram.Statements) > 0) {
+			diagram, err := requestedDiagram(fileName, pendingDiagram)
+			if err != nil {
+				fmt.Printf("Unable to draw diagram in %s\n%v\n", fileName, err)
+				os.Exit(1)
+			}
+			doc.Blocks = append(doc.Blocks, diagram)
+			pendingDiagram = nil
+		}
+		doc.Blocks = append(doc.Blocks, block{Kind: sourceBlock, File: fileName, Line: sourceLine})
+	}
 
 	const OpenCodeBlock = 0b10
 	const CloseCodeBlock = 0b100
@@ -73,14 +342,36 @@ func file2md(fileName string) string {
 		var line = input.Text()
 		var action = 0
 		switch {
+		case strings.HasPrefix(strings.TrimSpace(line), testsDirective):
+			wrappers, err = parseTestsDirective(line)
+			if err != nil {
+				fmt.Printf("Invalid directive at %s:%d\n%v\n", fileName, lineCounter, err)
+				os.Exit(1)
+			}
+		case strings.HasPrefix(strings.TrimSpace(line), diagramDirective):
+			pendingDiagram, err = parseDiagramDirective(line, pendingDiagram)
+			if err != nil {
+				fmt.Printf("Invalid directive at %s:%d\n%v\n", fileName, lineCounter, err)
+				os.Exit(1)
+			}
+		case strings.HasPrefix(strings.TrimSpace(line), runDirective):
+			pendingRun, err = parseRunDirective(line)
+			if err != nil {
+				fmt.Printf("Invalid directive at %s:%d\n%v\n", fileName, lineCounter, err)
+				os.Exit(1)
+			}
+		case strings.HasPrefix(strings.TrimSpace(line), "//go2md:"):
+			// Directives are never rendered
 		case strings.HasPrefix(line, "// Ignore-On"):
 			ignoring = true
 			if insideCodeBlock {
+				insideCodeBlock = false
 				action = action | CloseCodeBlock
 			}
 		case strings.HasPrefix(line, "// Ignore-Off"):
 			ignoring = false
 			if insideCodeBlock {
+				insideCodeBlock = false
 				action = action | CloseCodeBlock
 			}
 		case strings.HasPrefix(line, "//"):
@@ -92,8 +383,29 @@ func file2md(fileName string) string {
 			}
 		case strings.HasPrefix(line, "func Test_") && !ignoring:
 			insideTestBlock = true
+			switch wrappers {
+			case "full":
+				if !insideCodeBlock {
+					action = action | OpenCodeBlock
+					insideCodeBlock = true
+				}
+				action = action | IncludeNormalLine
+			case "caption":
+				if insideCodeBlock {
+					insideCodeBlock = false
+					action = action | CloseCodeBlock
+				}
+				pendingCaption = funcName(line)
+			}
 		case strings.HasPrefix(line, "}") && !ignoring && insideTestBlock:
 			insideTestBlock = false
+			if wrappers == "full" {
+				if !insideCodeBlock {
+					action = action | OpenCodeBlock
+					insideCodeBlock = true
+				}
+				action = action | IncludeNormalLine
+			}
 		case strings.TrimSpace(line) != "" && !ignoring:
 			if !insideCodeBlock {
 				action = action | OpenCodeBlock
@@ -107,41 +419,86 @@ func file2md(fileName string) string {
 		case !ignoring:
 			action = action | IncludeLineFeed
 		}
+		if pendingRun != nil && pendingRun.Target == "" {
+			pendingRun.Target = funcName(line)
+		}
+		if pendingDiagram != nil && pendingDiagram.Target == "" {
+			pendingDiagram.Target = funcName(line)
+		}
 		if OpenCodeBlock == action&OpenCodeBlock {
-			mdString = mdString + "\n``` go\n"
+			doc.Blocks = append(doc.Blocks, block{Kind: codeBlock, Label: pendingCaption})
+			pendingCaption = ""
 			lastSourceLine = lineCounter
 		}
 		if CloseCodeBlock == action&CloseCodeBlock {
-			mdString = mdString + "```\n"
-			_, justFile := filepath.Split(fileName)
-			mdString = mdString + fmt.Sprintf("\n\nSource: [%s](%s%s#L%d) | [Top](#top)\n\n", justFile, srcRoot, fileName, lineCounter)
+			closeCodeBlock(lineCounter)
 		}
 		if IncludeMarkdown == action&IncludeMarkdown {
-			if strings.HasPrefix(line, "// ") {
-				mdString = mdString + line[3:] + "\n"
+			if h, ok := parseHeading(line); ok {
+				wrappers = testWrappers
+				doc.Blocks = append(doc.Blocks, block{Kind: headingBlock, Level: h.Level, Lines: []string{h.Title}, Line: lineCounter})
+			} else if kind, text, ok := parseAdmonition(line); ok {
+				doc.Blocks = append(doc.Blocks, block{Kind: admonitionBlock, Label: kind})
+				if text != "" {
+					doc.add(admonitionBlock, text)
+				}
+			} else if last := len(doc.Blocks) - 1; strings.HasPrefix(line, "// ") && last >= 0 && doc.Blocks[last].Kind == admonitionBlock {
+				// The admonition runs to the end of its paragraph
+				doc.add(admonitionBlock, line[3:])
+			} else if strings.HasPrefix(line, "// ") {
+				doc.add(proseBlock, line[3:])
 			} else if line == "//" {
-				mdString = mdString + "\n"
+				doc.add(proseBlock, "")
 			}
 		}
 		if IncludeNormalLine == action&IncludeNormalLine {
-			mdString = mdString + line + "\n"
+			doc.add(codeBlock, line)
+			if message, found := compilerMessages[lineCounter]; found {
+				pendingMessages = append(pendingMessages, message)
+			}
 		}
 		if IncludeTestLine == action&IncludeTestLine {
 			if strings.Contains(line, "assert.") {
-				line = assert2equality(line)
+				if blankAnswers {
+					line = workbookLine(line)
+				}
+				comment := ""
+				if showValues {
+					comment = valuesComment(line, values[lineCounter])
+				}
+				line = annotateValues(assert2equality(line), comment)
+			}
+			doc.add(codeBlock, line)
+			if wrappers != "full" {
+				testLines[len(doc.Blocks[len(doc.Blocks)-1].Lines)-1] = true
+			}
+			if message, found := compilerMessages[lineCounter]; found {
+				pendingMessages = append(pendingMessages, message)
 			}
-			mdString = mdString + line[1:] + "\n"
 		}
 		if IncludeLineFeed == action&IncludeLineFeed {
-			mdString = mdString + "\n"
+			if insideCodeBlock {
+				doc.add(codeBlock, "")
+			} else {
+				doc.add(proseBlock, "")
+			}
 		}
 	}
 	if insideCodeBlock {
-		mdString = mdString + "```\n"
-		_, justFile := filepath.Split(fileName)
-		mdString = mdString + fmt.Sprintf("\n\nSource: [%s](%s%s#L%d) | [Top](#top)\n\n", justFile, srcRoot, fileName, lastSourceLine)
+		closeCodeBlock(lastSourceLine)
 	}
-	return mdString
+	return doc
+}
+
+// runBlocks runs the function targeted by a //go2md:run directive and
+// returns blocks showing what it printed.
+func runBlocks(fileName string, request *runRequest) []block {
+	result, err := runSnippet(fileName, request)
+	if err != nil {
+		fmt.Printf("Unable to run %s in %s\n%v\n", request.Target, fileName, err)
+		os.Exit(1)
+	}
+	return resultBlocks(result)
 }
 
 func assert2equality(originalLine string) string {
@@ -186,10 +543,6 @@ func assert2equality(originalLine string) string {
 		b = line[commaPosition+2:lastCommaPosition] + line[lastCommaPosition+1:]
 	}
 
-	symbol := "⇔"
-	if !equals {
-		symbol = "⇎"
-	}
-	return fmt.Sprintf("%s %s %s", a, symbol, b)
+	return symbols.equality(a, b, equals)
 
 }
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Grading runs the exercise packages written by `go2md exercises` with a
+// recording assert (see values.go) and checks each exercise line. An
+// exercise is solved once its assertion passes on every call and its
+// TODO marker has been removed, so placeholders that happen to be the
+// right answer do not count until the trainee says so.
+
+const progressFileName = "progress.json"
+
+type exerciseProgress struct {
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+	Chapter  string `json:"chapter"`
+	Section  string `json:"section"`
+	Solved   bool   `json:"solved"`
+	SolvedAt string `json:"solvedAt,omitempty"`
+}
+
+type progress struct {
+	Updated   string             `json:"updated"`
+	Exercises []exerciseProgress `json:"exercises"`
+}
+
+func gradeCommand(args []string) int {
+	outDir := defaultExercisesDir
+	progressFile := ""
+	gradeFiles := []string{}
+	skip := false
+	for index, arg := range args {
+		if arg == "-o" || arg == "-p" {
+			if index >= len(args)-1 {
+				fmt.Printf("No arguments after %s\n", arg)
+				return 1
+			}
+			if arg == "-o" {
+				outDir = args[index+1]
+			} else {
+				progressFile = args[index+1]
+			}
+			skip = true
+		} else if !skip {
+			gradeFiles = append(gradeFiles, arg)
+		} else {
+			skip = false
+		}
+	}
+	if len(gradeFiles) == 0 {
+		fmt.Printf("Usage:\n\n")
+		fmt.Printf("    go2md grade [-o <DIR>] [-p <PROGRESS_FILE>] <file_1> <file_2> ... <file_n>\n\n")
+		fmt.Printf("The files are the chapter files the exercises in DIR were generated from.\n")
+		fmt.Printf("Remove the %s marker from an exercise once it has been answered.\n", exerciseMarker)
+		return 0
+	}
+	if progressFile == "" {
+		progressFile = filepath.Join(outDir, progressFileName)
+	}
+
+	previous := loadProgress(progressFile)
+	now := time.Now().Format(time.RFC3339)
+	current := progress{Updated: now, Exercises: []exerciseProgress{}}
+	chapter := ""
+	for _, fileName := range gradeFiles {
+		exercises, nextChapter, err := gradeFile(fileName, outDir, chapter)
+		if err != nil {
+			fmt.Printf("Unable to grade file %s\n%v\n", fileName, err)
+			return 1
+		}
+		chapter = nextChapter
+		for _, exercise := range exercises {
+			if exercise.Solved {
+				exercise.SolvedAt = now
+				if solvedAt, found := previous[fmt.Sprintf("%s:%d", exercise.File, exercise.Line)]; found {
+					exercise.SolvedAt = solvedAt
+				}
+			}
+			current.Exercises = append(current.Exercises, exercise)
+		}
+	}
+
+	data, err := json.MarshalIndent(current, "", "  ")
+	if err != nil {
+		fmt.Printf("Unable to encode progress\n%v\n", err)
+		return 1
+	}
+	if err := os.WriteFile(progressFile, data, 0644); err != nil {
+		fmt.Printf("Unable to write file %s\n%v\n", progressFile, err)
+		return 1
+	}
+	os.Stdout.WriteString(progressReport(current))
+	return 0
+}
+
+// loadProgress returns when each exercise was first solved according to
+// a previous run, keyed by file:line. A missing file means no progress.
+func loadProgress(progressFile string) map[string]string {
+	solved := map[string]string{}
+	data, err := os.ReadFile(progressFile)
+	if err != nil {
+		return solved
+	}
+	var previous progress
+	if err := json.Unmarshal(data, &previous); err != nil {
+		return solved
+	}
+	for _, exercise := range previous.Exercises {
+		if exercise.Solved {
+			solved[fmt.Sprintf("%s:%d", exercise.File, exercise.Line)] = exercise.SolvedAt
+		}
+	}
+	return solved
+}
+
+// gradeFile grades the exercises generated from fileName. Exercises keep
+// the line numbers of the assertions they were generated from.
+func gradeFile(fileName string, outDir string, chapter string) ([]exerciseProgress, string, error) {
+	original, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, chapter, err
+	}
+	exerciseFile := filepath.Join(outDir, fileName)
+	edited, err := os.ReadFile(exerciseFile)
+	if err != nil {
+		return nil, chapter, err
+	}
+	lineLocations, nextChapter, err := locations(fileName, chapter)
+	if err != nil {
+		return nil, chapter, err
+	}
+	editedLines := strings.Split(string(edited), "\n")
+	exercises := []exerciseProgress{}
+	for i, line := range strings.Split(string(original), "\n") {
+		if _, found := exerciseLine(line); !found {
+			continue
+		}
+		exercises = append(exercises, exerciseProgress{
+			File:    fileName,
+			Line:    i + 1,
+			Chapter: lineLocations[i+1].Chapter,
+			Section: lineLocations[i+1].Section,
+		})
+	}
+	if len(exercises) == 0 {
+		return exercises, nextChapter, nil
+	}
+
+	recorded := recordedValues(exerciseFile)
+	for i := range exercises {
+		line := exercises[i].Line
+		values := recorded[line]
+		answered := line <= len(editedLines) && !strings.Contains(editedLines[line-1], exerciseMarker)
+		exercises[i].Solved = answered && values != nil && values.Failures == 0
+	}
+	return exercises, nextChapter, nil
+}
+
+// progressReport summarises progress per chapter and points at the
+// section holding the first unsolved exercise.
+func progressReport(current progress) string {
+	chapters := []string{}
+	solved := map[string]int{}
+	total := map[string]int{}
+	var next *exerciseProgress
+	for i, exercise := range current.Exercises {
+		if total[exercise.Chapter] == 0 {
+			chapters = append(chapters, exercise.Chapter)
+		}
+		total[exercise.Chapter]++
+		if exercise.Solved {
+			solved[exercise.Chapter]++
+		} else if next == nil {
+			next = &current.Exercises[i]
+		}
+	}
+	report := ""
+	allSolved, allTotal := 0, 0
+	for _, chapter := range chapters {
+		report = report + fmt.Sprintf("%-40s %4d/%-4d %3d%%\n", chapter, solved[chapter], total[chapter], 100*solved[chapter]/total[chapter])
+		allSolved += solved[chapter]
+		allTotal += total[chapter]
+	}
+	if allTotal > 0 {
+		report = report + fmt.Sprintf("%-40s %4d/%-4d %3d%%\n", "Total", allSolved, allTotal, 100*allSolved/allTotal)
+	}
+	if next != nil {
+		report = report + fmt.Sprintf("\nNext: %s / %s (%s:%d)\n", next.Chapter, next.Section, next.File, next.Line)
+	} else {
+		report = report + "\nAll exercises solved!\n"
+	}
+	return report
+}
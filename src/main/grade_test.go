@@ -0,0 +1,55 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const gradeChapter = `package pointers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// # Pointers
+// ## Dereferencing
+
+func Test_Pointer(t *testing.T) {
+	a := 1
+	p := &a
+	*p = 2
+	assert.Equal(t, 2, a)
+}
+`
+
+func Test_GradeAbsoluteDir(t *testing.T) {
+	root := t.TempDir()
+	fileName := filepath.Join(root, "src", "pointers", "pointers_test.go")
+	outDir := filepath.Join(root, "exercises")
+	if err := os.MkdirAll(filepath.Dir(fileName), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(fileName, []byte(gradeChapter), 0644); err != nil {
+		t.Fatal(err)
+	}
+	exerciseFile := filepath.Join(outDir, fileName)
+	if err := os.MkdirAll(filepath.Dir(exerciseFile), 0755); err != nil {
+		t.Fatal(err)
+	}
+	answered := strings.Replace(gradeChapter, "assert.Equal(t, 2, a)", "assert.Equal(t, 2, a) // answered", 1)
+	if err := os.WriteFile(exerciseFile, []byte(answered), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	exercises, _, err := gradeFile(fileName, outDir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(exercises) != 1 || !exercises[0].Solved {
+		t.Errorf("gradeFile(%s) = %+v; want one solved exercise", fileName, exercises)
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"os"
+	"strings"
+)
+
+// A header is the markdown file that opens the book (header.md), made of
+// YAML front matter with the book's metadata followed by the
+// introduction. Only flat "key: value" front matter is supported.
+type header struct {
+	Fields map[string]string
+	Keys   []string
+	Body   string
+}
+
+func readHeader(fileName string) (*header, error) {
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	h := &header{Fields: map[string]string{}, Body: symbols.legend(string(data))}
+	lines := strings.Split(string(data), "\n")
+	if len(lines) == 0 || strings.TrimSpace(lines[0]) != "---" {
+		return h, nil
+	}
+	for i := 1; i < len(lines); i++ {
+		line := lines[i]
+		if strings.TrimSpace(line) == "---" || strings.TrimSpace(line) == "..." {
+			h.Body = symbols.legend(strings.Join(lines[i+1:], "\n"))
+			return h, nil
+		}
+		colon := strings.Index(line, ":")
+		if colon < 0 {
+			continue
+		}
+		key := strings.TrimSpace(line[:colon])
+		value := strings.TrimSpace(line[colon+1:])
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		if _, found := h.Fields[key]; !found {
+			h.Keys = append(h.Keys, key)
+		}
+		h.Fields[key] = value
+	}
+	// No closing delimiter: this was not front matter after all
+	return &header{Fields: map[string]string{}, Body: symbols.legend(string(data))}, nil
+}
+
+// markdown2doc turns plain markdown, such as a header's body, into a
+// document of headings and prose.
+func markdown2doc(fileName string, markdown string) *document {
+	doc := &document{File: fileName}
+	insideFence := false
+	for _, line := range strings.Split(strings.TrimRight(markdown, "\n"), "\n") {
+		if strings.HasPrefix(line, "```") {
+			insideFence = !insideFence
+		}
+		if h, ok := parseHeading("// " + line); ok && !insideFence {
+			doc.Blocks = append(doc.Blocks, block{Kind: headingBlock, Level: h.Level, Lines: []string{h.Title}})
+			continue
+		}
+		doc.add(proseBlock, line)
+	}
+	return doc
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"path/filepath"
+	"strings"
+)
+
+// htmlPage holds the blocks rendered as one (X)HTML page. IDs gives each
+// heading block, by index, an identifier unique within the page; Pages
+// maps heading identifiers to the page holding them, so that links to
+// headings on other pages can be resolved.
+type htmlPage struct {
+	Blocks []block
+	IDs    map[int]string
+	Pages  map[string]string
+}
+
+// headingIDs assigns identifiers to the heading blocks, appending -1, -2,
+// and so on to repeated ones as pandoc does. Seen carries the identifiers
+// already in use.
+func headingIDs(blocks []block, seen map[string]int) map[int]string {
+	ids := map[int]string{}
+	for i, b := range blocks {
+		if b.Kind != headingBlock {
+			continue
+		}
+		id := headingID(b.Lines[0])
+		if count, found := seen[id]; found {
+			seen[id] = count + 1
+			id = fmt.Sprintf("%s-%d", id, count+1)
+		} else {
+			seen[id] = 0
+		}
+		ids[i] = id
+	}
+	return ids
+}
+
+// htmlBody renders the blocks of a page as XHTML.
+func htmlBody(page htmlPage) string {
+	body := ""
+	for i, b := range page.Blocks {
+		switch b.Kind {
+		case headingBlock:
+			level := b.Level
+			if level > 6 {
+				level = 6
+			}
+			body = body + fmt.Sprintf("<h%d id=\"%s\">%s</h%d>\n", level, page.IDs[i], page.inline(b.Lines[0]), level)
+		case proseBlock:
+			body = body + page.prose(b.Lines)
+		case codeBlock:
+			if b.Label != "" {
+				body = body + "<p class=\"test-caption\" id=\"" + html.EscapeString(b.Label) + "\"><code>" + html.EscapeString(b.Label) + "</code></p>\n"
+			}
+			body = body + htmlPre("go", b.Lines)
+		case outputBlock:
+			if b.Label != "" {
+				body = body + "<p class=\"output-label\"><strong>" + html.EscapeString(b.Label) + ":</strong></p>\n"
+			}
+			body = body + htmlPre("output", b.Lines)
+		case badgeBlock:
+			body = body + "<p class=\"go-version\" title=\"" + html.EscapeString(b.Label) + "\">" + html.EscapeString(b.Lines[0]) + "</p>\n"
+		case admonitionBlock:
+			body = body + "<div class=\"admonition " + strings.ToLower(b.Label) + "\">\n<p class=\"admonition-title\">" + html.EscapeString(b.Label) + "</p>\n" + page.prose(b.Lines) + "</div>\n"
+		case calloutBlock:
+			body = body + "<ul class=\"callouts\">\n"
+			for _, line := range b.Lines {
+				body = body + "<li>" + html.EscapeString(line) + "</li>\n"
+			}
+			body = body + "</ul>\n"
+		case diagramBlock:
+			body = body + "<div class=\"diagram\">\n" + diagramSVG(b.Lines) + "</div>\n"
+		case benchmarkBlock:
+			body = body + "<table class=\"benchmarks\">\n<caption>" + html.EscapeString(b.Label) + "</caption>\n"
+			for i, line := range b.Lines {
+				cell := "td"
+				if i == 0 {
+					cell = "th"
+				}
+				body = body + "<tr>"
+				for _, text := range strings.Split(line, "\t") {
+					body = body + "<" + cell + ">" + html.EscapeString(text) + "</" + cell + ">"
+				}
+				body = body + "</tr>\n"
+			}
+			body = body + "</table>\n"
+		case sourceBlock:
+			_, justFile := filepath.Split(b.File)
+			url := fmt.Sprintf("%s%s#L%d", srcRoot, b.File, b.Line)
+			body = body + fmt.Sprintf("<p class=\"source\">Source: <a href=\"%s\">%s</a></p>\n", html.EscapeString(url), html.EscapeString(justFile))
+		}
+	}
+	return body
+}
+
+func htmlPre(class string, lines []string) string {
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return "<pre class=\"" + class + "\"><code>" + html.EscapeString(strings.Join(lines, "\n")) + "</code></pre>\n"
+}
+
+func (page htmlPage) prose(lines []string) string {
+	body := ""
+	parts := splitProse(lines)
+	for i, part := range parts {
+		switch part.Kind {
+		case paragraphPart:
+			body = body + "<p>" + page.inline(strings.Join(part.Lines, "\n")) + "</p>\n"
+		case itemPart:
+			if i == 0 || parts[i-1].Kind != itemPart {
+				body = body + "<ul>\n"
+			}
+			body = body + "<li>" + page.inline(strings.Join(part.Lines, "\n")) + "</li>\n"
+			if i == len(parts)-1 || parts[i+1].Kind != itemPart {
+				body = body + "</ul>\n"
+			}
+		case fencePart:
+			body = body + htmlPre("text", part.Lines)
+		}
+	}
+	return body
+}
+
+func (page htmlPage) inline(text string) string {
+	return renderInline(text, inlineRenderer{
+		Code:   func(code string) string { return "<code>" + html.EscapeString(code) + "</code>" },
+		Emph:   func(text string) string { return "<em>" + text + "</em>" },
+		Strong: func(text string) string { return "<strong>" + text + "</strong>" },
+		Link: func(label string, target string) string {
+			if strings.HasPrefix(target, "#") {
+				target = page.Pages[target[1:]] + target
+			}
+			return "<a href=\"" + html.EscapeString(target) + "\">" + label + "</a>"
+		},
+		Text: html.EscapeString,
+	})
+}
@@ -0,0 +1,75 @@
+package main
+
+import "strings"
+
+// Test bodies are shown without the test function around them, so their
+// lines are dedented by the indentation they have in common within each
+// snippet. Lines continuing a raw string literal are taken literally:
+// they neither count towards the common indentation nor lose any of it.
+// With -tabwidth, tabs in snippets are expanded to spaces.
+
+var tabWidth = 0
+
+// dedent removes from the lines of code at the given indices the longest
+// run of leading whitespace they share, ignoring blank lines.
+func dedent(code []string, indices map[int]bool) {
+	raw := false
+	literal := map[int]bool{}
+	for i, line := range code {
+		literal[i] = raw
+		trailingComment(line, &raw)
+	}
+	common, first := "", true
+	for i, line := range code {
+		if !indices[i] || literal[i] || strings.TrimSpace(line) == "" {
+			continue
+		}
+		indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		if first {
+			common, first = indent, false
+			continue
+		}
+		n := 0
+		for n < len(common) && n < len(indent) && common[n] == indent[n] {
+			n++
+		}
+		common = common[:n]
+	}
+	for i := range code {
+		if indices[i] && !literal[i] {
+			code[i] = strings.TrimPrefix(code[i], common)
+		}
+	}
+}
+
+// expandTabs replaces the tabs in line with spaces up to the next
+// multiple of width.
+func expandTabs(line string, width int) string {
+	if width <= 0 || !strings.Contains(line, "\t") {
+		return line
+	}
+	expanded := []rune{}
+	for _, r := range line {
+		if r != '\t' {
+			expanded = append(expanded, r)
+			continue
+		}
+		expanded = append(expanded, ' ')
+		for len(expanded)%width != 0 {
+			expanded = append(expanded, ' ')
+		}
+	}
+	return string(expanded)
+}
+
+// applyTabWidth expands the tabs in the snippets of doc.
+func applyTabWidth(doc *document, width int) {
+	for i, b := range doc.Blocks {
+		if b.Kind != codeBlock {
+			continue
+		}
+		for j, line := range b.Lines {
+			doc.Blocks[i].Lines[j] = expandTabs(line, width)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_Dedent(t *testing.T) {
+	code := []string{
+		"var x = 1",
+		"    s := `first",
+		"second`",
+		"    f := func() {",
+		"",
+		"        g()",
+		"    }",
+	}
+	dedent(code, map[int]bool{1: true, 2: true, 3: true, 4: true, 5: true, 6: true})
+	expected := []string{
+		"var x = 1",
+		"s := `first",
+		"second`",
+		"f := func() {",
+		"",
+		"    g()",
+		"}",
+	}
+	if !reflect.DeepEqual(code, expected) {
+		t.Errorf("dedent gave %q; want %q", code, expected)
+	}
+}
+
+func Test_ExpandTabs(t *testing.T) {
+	if expanded := expandTabs("\tx\ty := 1", 4); expanded != "    x   y := 1" {
+		t.Errorf("expandTabs gave %q", expanded)
+	}
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// The LaTeX output is a complete document meant to be built with xelatex
+// or lualatex, which read UTF-8 natively. Snippets become listings; any
+// non-ASCII character in them is escaped out of the listing so that it
+// is typeset by the main font, and ⇔ and ⇎ become math symbols.
+
+const latexPreamble = `% Generated by go2md. Build with xelatex or lualatex.
+\documentclass[11pt]{report}
+\usepackage{fontspec}
+\usepackage{amssymb}
+\usepackage{xcolor}
+\usepackage{listings}
+\usepackage{tcolorbox}
+\usepackage[hidelinks]{hyperref}
+
+\lstdefinelanguage{Go}{
+  keywords={break,case,chan,const,continue,default,defer,else,fallthrough,
+    for,func,go,goto,if,import,interface,map,package,range,return,select,
+    struct,switch,type,var},
+  keywords=[2]{bool,byte,complex64,complex128,error,float32,float64,int,
+    int8,int16,int32,int64,rune,string,uint,uint8,uint16,uint32,uint64,
+    uintptr,true,false,nil,iota},
+  sensitive=true,
+  comment=[l]{//},
+  morecomment=[s]{/*}{*/},
+  morestring=[b]",
+  morestring=[b]',
+  morestring=[s]{` + "`" + `}{` + "`" + `}
+}
+\lstset{
+  language=Go,
+  basicstyle=\ttfamily\small,
+  keywordstyle=\bfseries\color{blue!60!black},
+  keywordstyle=[2]\color{teal!70!black},
+  commentstyle=\itshape\color{black!55},
+  stringstyle=\color{green!40!black},
+  escapeinside={(*@}{@*)},
+  columns=fullflexible,
+  keepspaces=true,
+  showstringspaces=false,
+  breaklines=true,
+  frame=single,
+  rulecolor=\color{black!25}
+}
+\lstdefinestyle{output}{language={},frame=leftline,basicstyle=\ttfamily\footnotesize}
+\lstdefinestyle{plain}{language={}}
+
+\newtcolorbox{admonition}[2]{colback=#1!4,colframe=#1!60!black,fonttitle=\bfseries,title=#2}
+\newcommand{\sourcenote}[2]{\noindent{\footnotesize Source\footnote{\href{#2}{\nolinkurl{#1}}}}\par}
+
+\begin{document}
+\tableofcontents
+`
+
+const latexEnd = `\end{document}
+`
+
+// latexAdmonitionColors gives the colour of the box of each kind of
+// admonition.
+var latexAdmonitionColors = map[string]string{"Note": "blue", "Warning": "red", "Tip": "green"}
+
+var latexSectioning = []string{"chapter", "section", "subsection", "subsubsection", "paragraph"}
+
+// renderLatex renders documents as a single LaTeX document.
+func renderLatex(docs []*document) string {
+	latex := latexPreamble
+	for _, doc := range docs {
+		latex = latex + latexBody(doc)
+	}
+	return latex + latexEnd
+}
+
+func latexBody(doc *document) string {
+	latex := ""
+	for _, b := range doc.Blocks {
+		switch b.Kind {
+		case headingBlock:
+			level := b.Level - 1
+			if level >= len(latexSectioning) {
+				level = len(latexSectioning) - 1
+			}
+			latex = latex + fmt.Sprintf("\n\\%s{%s}\\label{%s}\n", latexSectioning[level], latexInline(b.Lines[0]), headingID(b.Lines[0]))
+		case proseBlock:
+			latex = latex + latexProse(b.Lines)
+		case codeBlock:
+			options := ""
+			if b.Label != "" {
+				options = "caption={" + latexEscape(b.Label) + "},label={" + b.Label + "}"
+			}
+			latex = latex + latexListing(options, b.Lines)
+		case outputBlock:
+			if b.Label != "" {
+				latex = latex + "\n\\noindent\\textbf{" + latexEscape(b.Label) + ":}\n"
+			}
+			latex = latex + latexListing("style=output", b.Lines)
+		case badgeBlock:
+			latex = latex + "\n\\noindent\\fbox{\\footnotesize " + latexEscape(b.Lines[0]) + "}\n"
+		case admonitionBlock:
+			latex = latex + "\n\\begin{admonition}{" + latexAdmonitionColors[b.Label] + "}{" + latexEscape(b.Label) + "}\n" + latexProse(b.Lines) + "\\end{admonition}\n"
+		case calloutBlock:
+			latex = latex + "\n\\begin{itemize}\n"
+			for _, line := range b.Lines {
+				marker, text, _ := strings.Cut(line, " ")
+				latex = latex + "\\item[" + latexCallout(marker) + "] " + latexEscape(text) + "\n"
+			}
+			latex = latex + "\\end{itemize}\n"
+		case diagramBlock:
+			latex = latex + latexListing("style=plain", b.Lines)
+		case benchmarkBlock:
+			latex = latex + "\n\\begin{table}[h]\n\\centering\n\\caption{" + latexEscape(b.Label) + "}\n\\begin{tabular}{l" + strings.Repeat("r", strings.Count(b.Lines[0], "\t")) + "}\n"
+			for i, line := range b.Lines {
+				cells := strings.Split(line, "\t")
+				for j := range cells {
+					cells[j] = latexEscape(cells[j])
+				}
+				latex = latex + strings.Join(cells, " & ") + " \\\\\n"
+				if i == 0 {
+					latex = latex + "\\hline\n"
+				}
+			}
+			latex = latex + "\\end{tabular}\n\\end{table}\n"
+		case sourceBlock:
+			_, justFile := filepath.Split(b.File)
+			url := fmt.Sprintf("%s%s#L%d", srcRoot, b.File, b.Line)
+			url = strings.NewReplacer("#", "\\#", "%", "\\%").Replace(url)
+			latex = latex + fmt.Sprintf("\n\\sourcenote{%s}{%s}\n", justFile, url)
+		}
+	}
+	return latex
+}
+
+func latexListing(options string, lines []string) string {
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if options != "" {
+		options = "[" + options + "]"
+	}
+	latex := "\n\\begin{lstlisting}" + options + "\n"
+	for _, line := range lines {
+		latex = latex + latexCode(line) + "\n"
+	}
+	return latex + "\\end{lstlisting}\n"
+}
+
+// latexCallout typesets a callout marker as a circled number.
+func latexCallout(marker string) string {
+	if n, ok := calloutNumber(marker); ok {
+		return fmt.Sprintf("\\textcircled{\\scriptsize %d}", n)
+	}
+	return latexEscape(marker)
+}
+
+// latexCode escapes the non-ASCII characters of a line of code out of
+// its listing.
+func latexCode(line string) string {
+	code := ""
+	for _, r := range line {
+		switch {
+		case r == '⇔':
+			code = code + "(*@$\\Leftrightarrow$@*)"
+		case r == '⇎':
+			code = code + "(*@$\\nLeftrightarrow$@*)"
+		case r >= '①' && r <= '⑳':
+			code = code + "(*@" + latexCallout(string(r)) + "@*)"
+		case r > 127:
+			code = code + "(*@" + string(r) + "@*)"
+		default:
+			code = code + string(r)
+		}
+	}
+	return code
+}
+
+// latexProse renders markdown prose: paragraphs, bullet lists and
+// fenced blocks.
+func latexProse(lines []string) string {
+	latex := ""
+	parts := splitProse(lines)
+	for i, part := range parts {
+		switch part.Kind {
+		case paragraphPart:
+			latex = latex + "\n"
+			for _, line := range part.Lines {
+				latex = latex + latexInline(line) + "\n"
+			}
+		case itemPart:
+			if i == 0 || parts[i-1].Kind != itemPart {
+				latex = latex + "\n\\begin{itemize}\n"
+			}
+			latex = latex + "\\item " + latexInline(strings.Join(part.Lines, " ")) + "\n"
+			if i == len(parts)-1 || parts[i+1].Kind != itemPart {
+				latex = latex + "\\end{itemize}\n"
+			}
+		case fencePart:
+			latex = latex + latexListing("style=plain", part.Lines)
+		}
+	}
+	return latex
+}
+
+var latexInlineRenderer = inlineRenderer{
+	Code:   func(code string) string { return "\\texttt{" + latexEscape(code) + "}" },
+	Emph:   func(text string) string { return "\\emph{" + text + "}" },
+	Strong: func(text string) string { return "\\textbf{" + text + "}" },
+	Link: func(label string, target string) string {
+		if strings.HasPrefix(target, "#") {
+			return "\\hyperref[" + target[1:] + "]{" + label + "}"
+		}
+		return "\\href{" + strings.NewReplacer("#", "\\#", "%", "\\%").Replace(target) + "}{" + label + "}"
+	},
+	Text: latexEscape,
+}
+
+func latexInline(text string) string {
+	return renderInline(text, latexInlineRenderer)
+}
+
+func latexEscape(text string) string {
+	return strings.NewReplacer(
+		"\\", "\\textbackslash{}",
+		"{", "\\{",
+		"}", "\\}",
+		"$", "\\$",
+		"&", "\\&",
+		"#", "\\#",
+		"^", "\\textasciicircum{}",
+		"_", "\\_",
+		"%", "\\%",
+		"~", "\\textasciitilde{}",
+		"<", "\\textless{}",
+		">", "\\textgreater{}",
+		"⇔", "$\\Leftrightarrow$",
+		"⇎", "$\\nLeftrightarrow$",
+	).Replace(text)
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// The linter catches authoring mistakes that still render: sections
+// without code, packages named after another directory, deprecated APIs
+// (see deprecations.go), backticked names in prose that do not resolve
+// (see references.go), headings that skip a level and assertions that the
+// converter leaves as they are.
+
+type diagnostic struct {
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Check   string `json:"check"`
+	Message string `json:"message"`
+}
+
+var codeSpanPattern = regexp.MustCompile("`([^`]+)`")
+
+func lintCommand(args []string) int {
+	jsonOutput := false
+	lintFiles := []string{}
+	for _, arg := range args {
+		if arg == "-json" {
+			jsonOutput = true
+		} else {
+			lintFiles = append(lintFiles, arg)
+		}
+	}
+	if len(lintFiles) == 0 {
+		fmt.Printf("Usage:\n\n")
+		fmt.Printf("    go2md lint [-json] <file_1> <file_2> ... <file_n>\n\n")
+		return 0
+	}
+	diagnostics := []diagnostic{}
+	for _, fileName := range lintFiles {
+		found, err := lintFile(fileName)
+		if err != nil {
+			fmt.Printf("Unable to lint file %s\n%v\n", fileName, err)
+			return 1
+		}
+		diagnostics = append(diagnostics, found...)
+	}
+	if jsonOutput {
+		data, _ := json.MarshalIndent(diagnostics, "", "  ")
+		fmt.Printf("%s\n", data)
+	} else {
+		for _, d := range diagnostics {
+			fmt.Printf("%s:%d: %s: %s\n", d.File, d.Line, d.Check, d.Message)
+		}
+	}
+	if len(diagnostics) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// lintFile returns the diagnostics of fileName in line order.
+func lintFile(fileName string) ([]diagnostic, error) {
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	lines := strings.Split(string(data), "\n")
+	diagnostics := []diagnostic{}
+	report := func(line int, check string, format string, args ...interface{}) {
+		diagnostics = append(diagnostics, diagnostic{File: fileName, Line: line, Check: check, Message: fmt.Sprintf(format, args...)})
+	}
+
+	isCode, isTest := renderedCode(lines)
+	for i, line := range lines {
+		if isTest[i+1] && strings.Contains(line, "assert.") && !rewritable(line) {
+			report(i+1, "assertion", "assertion not rewritten: %s", strings.TrimSpace(line))
+		}
+	}
+
+	found, err := headings(fileName)
+	if err != nil {
+		return nil, err
+	}
+	for i, h := range found {
+		if i > 0 && h.Level > found[i-1].Level+1 {
+			report(h.Line, "heading-level", "heading %q jumps from level %d to level %d", h.Title, found[i-1].Level, h.Level)
+		}
+		end := len(lines)
+		for _, next := range found[i+1:] {
+			if next.Level <= h.Level {
+				end = next.Line - 1
+				break
+			}
+		}
+		hasCode := false
+		for n := h.Line + 1; n <= end && !hasCode; n++ {
+			hasCode = isCode[n]
+		}
+		if !hasCode {
+			report(h.Line, "empty-section", "section %q has no code", h.Title)
+		}
+	}
+
+	pkg, mainFunc, err := packageMain(fileName)
+	if err != nil {
+		return nil, err
+	}
+	dir, _ := filepath.Split(fileName)
+	dirName := filepath.Base(filepath.Clean(dir))
+	if pkg != dirName && strings.TrimSuffix(pkg, "_test") != dirName && !(pkg == "main" && mainFunc) {
+		line := 1
+		for i, l := range lines {
+			if strings.HasPrefix(l, "package ") {
+				line = i + 1
+				break
+			}
+		}
+		report(line, "package", "package %s does not match directory %s", pkg, dirName)
+	}
+
+	deprecations, err := deprecatedUses(fileName)
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range deprecations {
+		report(d.Line, "deprecated", "%s", d.Text())
+	}
+
+	references, err := checkReferences(fileName)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range references {
+		report(e.Line, "identifier", "%s", e.Message())
+	}
+
+	sort.SliceStable(diagnostics, func(a, b int) bool {
+		return diagnostics[a].Line < diagnostics[b].Line
+	})
+	return diagnostics, nil
+}
+
+// renderedCode tells which lines (1-based) file2doc renders as code, and
+// which of those belong to the body of a test.
+func renderedCode(lines []string) ([]bool, []bool) {
+	isCode := make([]bool, len(lines)+1)
+	isTest := make([]bool, len(lines)+1)
+	ignoring, insideTest := false, false
+	for i, line := range lines {
+		switch {
+		case strings.HasPrefix(strings.TrimSpace(line), "//go2md:"):
+		case strings.HasPrefix(line, "// Ignore-On"):
+			ignoring = true
+		case strings.HasPrefix(line, "// Ignore-Off"):
+			ignoring = false
+		case strings.HasPrefix(line, "//"):
+			ignoring = false
+		case strings.HasPrefix(line, "func Test_") && !ignoring:
+			insideTest = true
+		case strings.HasPrefix(line, "}") && !ignoring && insideTest:
+			insideTest = false
+		case strings.TrimSpace(line) != "" && !ignoring:
+			isCode[i+1] = true
+			isTest[i+1] = insideTest
+		}
+	}
+	return isCode, isTest
+}
+
+// rewritable tells whether assert2equality turns line into an equality.
+func rewritable(line string) bool {
+	return assert2equality(line) != line
+}
+
+// packageMain returns the package of fileName and whether the package
+// declares a main function.
+func packageMain(fileName string) (string, bool, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, fileName, nil, 0)
+	if err != nil {
+		return "", false, err
+	}
+	dir, _ := filepath.Split(fileName)
+	siblings, _ := filepath.Glob(filepath.Join(dir, "*.go"))
+	for _, sibling := range siblings {
+		other, err := parser.ParseFile(fset, sibling, nil, 0)
+		if err != nil || other.Name.Name != file.Name.Name {
+			continue
+		}
+		for _, decl := range other.Decls {
+			if funcDecl, ok := decl.(*ast.FuncDecl); ok && funcDecl.Recv == nil && funcDecl.Name.Name == "main" {
+				return file.Name.Name, true, nil
+			}
+		}
+	}
+	return file.Name.Name, false, nil
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// renderMarkdown renders a document as pandoc markdown.
+func renderMarkdown(doc *document) string {
+	mdString := ""
+	for _, b := range doc.Blocks {
+		switch b.Kind {
+		case headingBlock:
+			mdString = mdString + strings.Repeat("#", b.Level) + " " + b.Lines[0] + "\n"
+		case proseBlock:
+			mdString = mdString + strings.Join(b.Lines, "\n") + "\n"
+		case codeBlock:
+			if b.Label != "" {
+				mdString = mdString + "\n[`" + b.Label + "`]{#" + b.Label + " .test-caption}\n"
+			}
+			mdString = mdString + "\n``` go\n" + strings.Join(b.Lines, "\n") + "\n```\n"
+		case outputBlock:
+			if b.Label != "" {
+				mdString = mdString + "\n" + b.Label + ":\n"
+			}
+			mdString = mdString + "\n``` text\n" + strings.Join(b.Lines, "\n") + "\n```\n"
+		case badgeBlock:
+			mdString = mdString + "\n[" + b.Lines[0] + "]{.go-version}\n\n"
+		case admonitionBlock:
+			// Pandoc gives the div a class but no title
+			title := "**" + b.Label + ":**"
+			if len(b.Lines) > 0 {
+				title = title + " " + b.Lines[0]
+			}
+			mdString = mdString + "\n::: " + strings.ToLower(b.Label) + "\n" + strings.Join(append([]string{title}, b.Lines[min(1, len(b.Lines)):]...), "\n") + "\n:::\n\n"
+		case calloutBlock:
+			mdString = mdString + "\n"
+			for _, line := range b.Lines {
+				mdString = mdString + "* " + markdownEscape(line) + "\n"
+			}
+		case diagramBlock:
+			mdString = mdString + "\n``` mermaid\n" + strings.Join(b.Lines, "\n") + "\n```\n"
+		case benchmarkBlock:
+			mdString = mdString + "\n"
+			for i, line := range b.Lines {
+				mdString = mdString + "| " + strings.ReplaceAll(markdownEscape(line), "\t", " | ") + " |\n"
+				if i == 0 {
+					mdString = mdString + "|:--" + strings.Repeat("|--:", strings.Count(line, "\t")) + "|\n"
+				}
+			}
+			mdString = mdString + "\n: " + markdownEscape(b.Label) + "\n\n"
+		case sourceBlock:
+			_, justFile := filepath.Split(b.File)
+			mdString = mdString + fmt.Sprintf("\n\nSource: [%s](%s%s#L%d) | [Top](#top)\n\n", justFile, srcRoot, b.File, b.Line)
+		}
+	}
+	return mdString
+}
+
+// markdownEscape escapes the characters of plain text that pandoc would
+// take as markup.
+func markdownEscape(text string) string {
+	return strings.NewReplacer(
+		"\\", "\\\\",
+		"`", "\\`",
+		"*", "\\*",
+		"_", "\\_",
+		"^", "\\^",
+		"~", "\\~",
+		"[", "\\[",
+		"]", "\\]",
+		"<", "\\<",
+		"$", "\\$",
+	).Replace(text)
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// A commented-out line is expected not to compile when it carries the
+// "Won't compile" marker or when it follows a //go2md:nocompile directive.
+const noCompileMarker = "Won't compile"
+const noCompileDirective = "//go2md:nocompile"
+
+type noCompileResult struct {
+	Line     int
+	Code     string
+	Compiles bool
+	Message  string
+	// Undefined is set when the line fails only on names it does not
+	// define, which seldom is the reason it is marked for
+	Undefined bool
+}
+
+// One importer is shared across checks so that standard library
+// packages are only type-checked once per run.
+var sourceImporter types.Importer
+
+// A failureCachingImporter remembers the imports that failed as well,
+// such as testify where it is not installed, which the source importer
+// would otherwise look up again, at length, for every package.
+type failureCachingImporter struct {
+	importer types.ImporterFrom
+	failed   map[string]error
+}
+
+func newSourceImporter(fset *token.FileSet) types.Importer {
+	return &failureCachingImporter{importer: importer.ForCompiler(fset, "source", nil).(types.ImporterFrom), failed: map[string]error{}}
+}
+
+func (c *failureCachingImporter) Import(path string) (*types.Package, error) {
+	return c.ImportFrom(path, "", 0)
+}
+
+func (c *failureCachingImporter) ImportFrom(path string, dir string, mode types.ImportMode) (*types.Package, error) {
+	if err, found := c.failed[path]; found {
+		return nil, err
+	}
+	pkg, err := c.importer.ImportFrom(path, dir, mode)
+	if err != nil {
+		c.failed[path] = err
+	}
+	return pkg, err
+}
+
+func nocompileCommand(args []string) int {
+	if len(args) == 0 {
+		fmt.Printf("Usage:\n\n")
+		fmt.Printf("    go2md nocompile <file_1> <file_2> ... <file_n>\n\n")
+		return 0
+	}
+	failures := 0
+	for _, fileName := range args {
+		results, err := checkNoCompile(fileName)
+		if err != nil {
+			fmt.Printf("Unable to check file %s\n%v\n", fileName, err)
+			return 1
+		}
+		for _, result := range results {
+			if result.Compiles {
+				failures++
+				fmt.Printf("%s:%d: compiles but is marked as not compiling: %s\n", fileName, result.Line, result.Code)
+			} else if result.Undefined {
+				failures++
+				fmt.Printf("%s:%d: only fails on an undefined name: %s\n", fileName, result.Line, result.Message)
+			} else {
+				fmt.Printf("%s:%d: ok: %s\n", fileName, result.Line, result.Message)
+			}
+		}
+	}
+	if failures > 0 {
+		return 1
+	}
+	return 0
+}
+
+// noCompileLines returns the line numbers (1-based) of the commented-out
+// statements in lines that are expected not to compile.
+func noCompileLines(lines []string) []int {
+	found := []int{}
+	directive := false
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == noCompileDirective {
+			directive = true
+			continue
+		}
+		if strings.HasPrefix(trimmed, "// ") && (directive || strings.Contains(trimmed, noCompileMarker)) {
+			found = append(found, i+1)
+		}
+		directive = false
+	}
+	return found
+}
+
+// uncomment removes the leading "// " from a commented-out line while
+// preserving its indentation, so that column numbers stay meaningful.
+func uncomment(line string) string {
+	index := strings.Index(line, "// ")
+	return line[:index] + line[index+3:]
+}
+
+// checkNoCompile uncomments each line marked as not compiling in a copy
+// of fileName and type-checks it along with the rest of its package.
+// Errors already present in the unmodified package (such as imports that
+// cannot be resolved) are discounted, so that a line only counts as not
+// compiling when it introduces errors of its own. A line whose errors are
+// all undefined names is flagged, as it likely fails for another reason
+// than the one the prose gives.
+func checkNoCompile(fileName string) ([]noCompileResult, error) {
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	lines := strings.Split(string(data), "\n")
+	results := []noCompileResult{}
+	markedLines := noCompileLines(lines)
+	if len(markedLines) == 0 {
+		return results, nil
+	}
+	baseline, err := typeCheckErrors(fileName, string(data))
+	if err != nil {
+		return nil, err
+	}
+	known := map[string]bool{}
+	for _, e := range baseline {
+		known[errorKey(e)] = true
+	}
+	for _, lineNumber := range markedLines {
+		copied := make([]string, len(lines))
+		copy(copied, lines)
+		copied[lineNumber-1] = uncomment(lines[lineNumber-1])
+		result := noCompileResult{
+			Line: lineNumber,
+			Code: strings.TrimSpace(copied[lineNumber-1]),
+		}
+		errs, err := typeCheckErrors(fileName, strings.Join(copied, "\n"))
+		if err != nil {
+			// A syntax error also means the line does not compile
+			result.Message = err.Error()
+			results = append(results, result)
+			continue
+		}
+		fresh := []types.Error{}
+		for _, e := range errs {
+			if !known[errorKey(e)] {
+				fresh = append(fresh, e)
+			}
+		}
+		if len(fresh) == 0 {
+			result.Compiles = true
+		} else {
+			result.Undefined = true
+			for _, e := range fresh {
+				if !strings.HasPrefix(e.Msg, "undefined: ") {
+					result.Undefined = false
+				}
+			}
+			// Prefer an error reported on the uncommented line itself
+			chosen := fresh[0]
+			for _, e := range fresh {
+				if e.Fset.Position(e.Pos).Line == lineNumber {
+					chosen = e
+					break
+				}
+			}
+			result.Message = compilerMessage(chosen)
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// errorKey identifies an error by line and message. Uncommenting a line
+// never shifts the lines around it, so errors elsewhere keep their key.
+func errorKey(e types.Error) string {
+	return fmt.Sprintf("%d:%s", e.Fset.Position(e.Pos).Line, e.Msg)
+}
+
+func compilerMessage(e types.Error) string {
+	position := e.Fset.Position(e.Pos)
+	_, justFile := filepath.Split(position.Filename)
+	return fmt.Sprintf("%s:%d:%d: %s", justFile, position.Line, position.Column, e.Msg)
+}
+
+// typeCheckErrors type-checks src as the contents of fileName together
+// with the other files in the same directory that declare the same
+// package. Type errors are returned; a syntax error in src is returned
+// as err.
+func typeCheckErrors(fileName string, src string) ([]types.Error, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, fileName, src, 0)
+	if err != nil {
+		return nil, err
+	}
+	astFiles := []*ast.File{file}
+	dir, justFile := filepath.Split(fileName)
+	siblings, _ := filepath.Glob(filepath.Join(dir, "*.go"))
+	sort.Strings(siblings)
+	for _, sibling := range siblings {
+		if _, name := filepath.Split(sibling); name == justFile {
+			continue
+		}
+		other, err := parser.ParseFile(fset, sibling, nil, 0)
+		if err != nil || other.Name.Name != file.Name.Name {
+			continue
+		}
+		astFiles = append(astFiles, other)
+	}
+	if sourceImporter == nil {
+		sourceImporter = newSourceImporter(fset)
+	}
+	errs := []types.Error{}
+	config := types.Config{
+		Importer: sourceImporter,
+		Error: func(err error) {
+			if typeErr, ok := err.(types.Error); ok {
+				errs = append(errs, typeErr)
+			}
+		},
+	}
+	config.Check(file.Name.Name, fset, astFiles, nil)
+	return errs, nil
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_CheckNoCompile(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "chapter_test.go")
+	source := `package chapter
+
+func receiveOnly(c <-chan string) {
+	// c <- msg // Won't compile
+	// c <- "message" // Won't compile
+	// x := 1 // Won't compile
+}
+`
+	if err := os.WriteFile(fileName, []byte(source), 0644); err != nil {
+		t.Fatal(err)
+	}
+	results, err := checkNoCompile(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("checkNoCompile gave %+v; want three results", results)
+	}
+	if r := results[0]; r.Compiles || !r.Undefined {
+		t.Errorf("undefined name: %+v; want it flagged", r)
+	}
+	if r := results[1]; r.Compiles || r.Undefined || !strings.Contains(r.Message, "receive-only") {
+		t.Errorf("send to a receive-only channel: %+v; want its error", r)
+	}
+	if r := results[2]; r.Compiles || r.Undefined {
+		t.Errorf("unused variable: %+v; want its error", r)
+	}
+}
@@ -0,0 +1,275 @@
+package main
+
+import (
+	"encoding/json"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// The notebook output turns each chapter file into a Jupyter notebook for
+// the GoNB kernel. Prose becomes markdown cells, declarations become code
+// cells and each test's body becomes a cell run as main (GoNB's %%). A
+// setup cell brings in the file's imports along with stand-ins for t and
+// for testify's assert that print the outcome of every check, so that the
+// tests run unchanged. Benchmarks, which need the testing package, are
+// left out.
+
+const defaultNotebooksDir = "notebooks"
+
+const notebookSetupSource = `import (
+	"fmt"
+	"reflect"
+)
+
+type notebookT struct{}
+
+func (notebookT) Error(args ...interface{})                 { fmt.Println(append([]interface{}{"✘"}, args...)...) }
+func (notebookT) Errorf(format string, args ...interface{}) { fmt.Printf("✘ "+format+"\n", args...) }
+func (notebookT) Log(args ...interface{})                   { fmt.Println(args...) }
+func (notebookT) Logf(format string, args ...interface{})   { fmt.Printf(format+"\n", args...) }
+
+var t notebookT
+
+type notebookAssertions struct{}
+
+var assert notebookAssertions
+
+func (notebookAssertions) Equal(_ notebookT, expected, actual interface{}, _ ...interface{}) bool {
+	passed := reflect.DeepEqual(expected, actual)
+	notebookCheck(passed, "⇔", expected, actual)
+	return passed
+}
+
+func (notebookAssertions) NotEqual(_ notebookT, expected, actual interface{}, _ ...interface{}) bool {
+	passed := !reflect.DeepEqual(expected, actual)
+	notebookCheck(passed, "⇎", expected, actual)
+	return passed
+}
+
+func notebookCheck(passed bool, symbol string, expected, actual interface{}) {
+	mark := "✔"
+	if !passed {
+		mark = "✘"
+	}
+	fmt.Printf("%s %#v %s %#v\n", mark, expected, symbol, actual)
+}`
+
+type notebook struct {
+	Cells         []map[string]interface{} `json:"cells"`
+	Metadata      notebookMetadata         `json:"metadata"`
+	NBFormat      int                      `json:"nbformat"`
+	NBFormatMinor int                      `json:"nbformat_minor"`
+}
+
+type notebookMetadata struct {
+	KernelSpec   map[string]string `json:"kernelspec"`
+	LanguageInfo map[string]string `json:"language_info"`
+}
+
+// file2notebook turns a chapter file into a notebook.
+func file2notebook(fileName string) (*notebook, error) {
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	nb := &notebook{
+		Metadata: notebookMetadata{
+			KernelSpec:   map[string]string{"display_name": "Go (gonb)", "language": "go", "name": "gonb"},
+			LanguageInfo: map[string]string{"name": "go"},
+		},
+		NBFormat:      4,
+		NBFormatMinor: 4,
+	}
+	setup, err := notebookImports(fileName)
+	if err != nil {
+		return nil, err
+	}
+	source := strings.NewReplacer(`"⇔"`, strconv.Quote(symbols.Equal), `"⇎"`, strconv.Quote(symbols.NotEqual)).Replace(notebookSetupSource)
+	nb.addCode(strings.Split(setup+source, "\n"), true)
+
+	// Tests that other tests call are declared as functions too
+	called := map[string]bool{}
+	for _, line := range strings.Split(string(data), "\n") {
+		if name := strings.TrimSpace(line); strings.HasPrefix(name, "Test_") && strings.HasSuffix(name, "(t)") {
+			called[strings.TrimSuffix(name, "(t)")] = true
+		}
+	}
+
+	kind, hidden := "", false
+	lines, declaration := []string{}, []string{}
+	flush := func() {
+		if len(declaration) > 0 {
+			nb.addCode(append(declaration, "}"), true)
+			declaration = []string{}
+		}
+		if kind == "test" {
+			body := map[int]bool{}
+			for i := 1; i < len(lines); i++ {
+				body[i] = true
+			}
+			dedent(lines, body)
+		}
+		for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+			lines = lines[:len(lines)-1]
+		}
+		for len(lines) > 0 && strings.TrimSpace(lines[0]) == "" {
+			lines = lines[1:]
+		}
+		if len(lines) > 0 && (kind != "test" || len(lines) > 1) {
+			if kind == "markdown" {
+				nb.Cells = append(nb.Cells, map[string]interface{}{
+					"cell_type": "markdown",
+					"metadata":  map[string]interface{}{},
+					"source":    notebookSource(lines),
+				})
+			} else {
+				nb.addCode(lines, hidden)
+			}
+		}
+		kind, hidden, lines = "", false, []string{}
+	}
+	start := func(newKind string, newHidden bool) {
+		if kind != newKind || hidden != newHidden {
+			flush()
+			kind, hidden = newKind, newHidden
+		}
+	}
+
+	ignoring, insideImports, benchmark := false, false, false
+	for _, line := range strings.Split(strings.TrimRight(string(data), "\n"), "\n") {
+		switch {
+		case strings.HasPrefix(strings.TrimSpace(line), "//go2md:"):
+			// Directives are never rendered
+		case strings.HasPrefix(line, "// Ignore-On"):
+			flush()
+			ignoring = true
+		case strings.HasPrefix(line, "// Ignore-Off"):
+			flush()
+			ignoring = false
+		case benchmark:
+			benchmark = !strings.HasPrefix(line, "}")
+		case strings.HasPrefix(line, "func Benchmark_"):
+			flush()
+			benchmark = true
+		case kind == "test":
+			if strings.HasPrefix(line, "}") {
+				flush()
+			} else {
+				lines = append(lines, line)
+				if len(declaration) > 0 {
+					declaration = append(declaration, line)
+				}
+			}
+		case insideImports:
+			insideImports = !strings.HasPrefix(line, ")")
+		case strings.HasPrefix(line, "package "):
+		case strings.HasPrefix(line, "import ("):
+			insideImports = true
+		case strings.HasPrefix(line, "import "):
+		case strings.HasPrefix(line, "//"):
+			ignoring = false
+			start("markdown", false)
+			lines = append(lines, strings.TrimPrefix(strings.TrimPrefix(line, "//"), " "))
+		case ignoring:
+			start("code", true)
+			lines = append(lines, line)
+		case strings.HasPrefix(line, "func Test_"):
+			flush()
+			kind = "test"
+			lines = append(lines, "%%")
+			if name := funcName(line); called[name] {
+				declaration = []string{"func " + name + "(t notebookT) {"}
+			}
+		case strings.TrimSpace(line) == "" && kind != "code":
+			if kind == "markdown" {
+				lines = append(lines, "")
+			}
+		default:
+			start("code", false)
+			lines = append(lines, line)
+		}
+	}
+	flush()
+	return nb, nil
+}
+
+// notebookImports returns an import declaration with the imports of
+// fileName but for testing and testify, which the setup cell stands in
+// for, and for those the setup cell already has.
+func notebookImports(fileName string) (string, error) {
+	file, err := parser.ParseFile(token.NewFileSet(), fileName, nil, parser.ImportsOnly)
+	if err != nil {
+		return "", err
+	}
+	imports := ""
+	for _, spec := range file.Imports {
+		path, _ := strconv.Unquote(spec.Path.Value)
+		if path == "testing" || path == "fmt" || path == "reflect" || spec.Path.Value == testifyImport {
+			continue
+		}
+		imports = imports + "\t"
+		if spec.Name != nil {
+			imports = imports + spec.Name.Name + " "
+		}
+		imports = imports + spec.Path.Value + "\n"
+	}
+	if imports == "" {
+		return "", nil
+	}
+	return "import (\n" + imports + ")\n\n", nil
+}
+
+func (nb *notebook) addCode(lines []string, hidden bool) {
+	metadata := map[string]interface{}{}
+	if hidden {
+		metadata["jupyter"] = map[string]bool{"source_hidden": true}
+	}
+	nb.Cells = append(nb.Cells, map[string]interface{}{
+		"cell_type":       "code",
+		"execution_count": nil,
+		"metadata":        metadata,
+		"outputs":         []interface{}{},
+		"source":          notebookSource(lines),
+	})
+}
+
+// notebookSource splits cell source into lines as Jupyter stores them,
+// each one but the last ending with a line feed.
+func notebookSource(lines []string) []string {
+	source := []string{}
+	for i, line := range lines {
+		if i < len(lines)-1 {
+			line = line + "\n"
+		}
+		source = append(source, line)
+	}
+	return source
+}
+
+// writeNotebooks writes a notebook per chapter file into dir.
+func writeNotebooks(chapterFiles []string, dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	for _, fileName := range chapterFiles {
+		nb, err := file2notebook(fileName)
+		if err != nil {
+			return err
+		}
+		data, err := json.MarshalIndent(nb, "", " ")
+		if err != nil {
+			return err
+		}
+		_, justFile := filepath.Split(fileName)
+		notebookFile := filepath.Join(dir, strings.TrimSuffix(justFile, ".go")+".ipynb")
+		if err := os.WriteFile(notebookFile, append(data, '\n'), 0644); err != nil {
+			return err
+		}
+		os.Stderr.WriteString(notebookFile + "\n")
+	}
+	return nil
+}
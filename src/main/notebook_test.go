@@ -0,0 +1,51 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_NotebookSkipsBenchmarks(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "chapter_test.go")
+	source := `package chapter
+
+import "testing"
+
+// ## Summing
+func Test_Sum(t *testing.T) {
+	assert.Equal(t, 3, 1+2)
+}
+
+// The below benchmark sums numbers:
+func Benchmark_Sum(b *testing.B) {
+	sum := 0
+	for i := 0; i < b.N; i++ {
+		sum = sum + i
+	}
+}
+
+var after = "after"
+`
+	if err := os.WriteFile(fileName, []byte(source), 0644); err != nil {
+		t.Fatal(err)
+	}
+	nb, err := file2notebook(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cells := []string{}
+	for _, cell := range nb.Cells {
+		cells = append(cells, strings.Join(cell["source"].([]string), ""))
+	}
+	all := strings.Join(cells, "\n")
+	if strings.Contains(all, "Benchmark_Sum") || strings.Contains(all, "b.N") {
+		t.Errorf("notebook holds the benchmark:\n%s", all)
+	}
+	for _, want := range []string{"assert.Equal(t, 3, 1+2)", "The below benchmark sums numbers:", `var after = "after"`} {
+		if !strings.Contains(all, want) {
+			t.Errorf("notebook lacks %q:\n%s", want, all)
+		}
+	}
+}
@@ -0,0 +1,289 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"html/template"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The playground serves a page per section with the section's tests in
+// editable text areas. Submitted code replaces the test in a copy of its
+// package, which is built as a temporary module with the recording assert
+// of values.go standing in for testify, so that nothing needs to be
+// downloaded. The test binary then runs with CPU, memory and wall clock
+// limits.
+
+const defaultPlaygroundAddr = "localhost:8080"
+
+type playgroundLimits struct {
+	Timeout time.Duration
+	CPU     int
+	Memory  int
+}
+
+type playgroundTest struct {
+	Name   string
+	Code   string
+	Output string
+}
+
+type playgroundPage struct {
+	Index    int
+	Chapter  string
+	Title    string
+	Prose    []string
+	Tests    []playgroundTest
+	Sections []*tuiSection
+	Previous int
+	Next     int
+}
+
+var playgroundTemplate = template.Must(template.New("playground").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{if .Title}}{{.Title}} - {{end}}{{.Chapter}}</title>
+<style>
+body { font-family: sans-serif; max-width: 60em; margin: 2em auto; }
+textarea, pre { font-family: monospace; font-size: 0.9em; width: 100%; }
+pre { background: #f4f4f4; padding: 0.5em; white-space: pre-wrap; }
+</style>
+</head>
+<body>
+{{if .Sections}}
+<h1>Go By Assertion Playground</h1>
+<ul>
+{{range $i, $s := .Sections}}<li><a href="/section/{{$i}}">{{$s.Chapter}}{{if $s.Title}}: {{$s.Title}}{{end}}</a></li>
+{{end}}</ul>
+{{else}}
+<p><a href="/">Index</a>{{if ge .Previous 0}} | <a href="/section/{{.Previous}}">Previous</a>{{end}}{{if ge .Next 0}} | <a href="/section/{{.Next}}">Next</a>{{end}}</p>
+<h1>{{.Chapter}}</h1>
+{{if .Title}}<h2>{{.Title}}</h2>{{end}}
+{{range .Prose}}<p>{{.}}</p>
+{{end}}
+{{range .Tests}}
+<form method="post" action="/section/{{$.Index}}#{{.Name}}">
+<h3 id="{{.Name}}">{{.Name}}</h3>
+<input type="hidden" name="test" value="{{.Name}}">
+<textarea name="code" rows="20" spellcheck="false">{{.Code}}</textarea>
+<p><button type="submit">Run</button></p>
+{{if .Output}}<pre>{{.Output}}</pre>{{end}}
+</form>
+{{else}}
+<p>No tests back this section.</p>
+{{end}}
+{{end}}
+</body>
+</html>
+`))
+
+func playgroundCommand(args []string) int {
+	addr := defaultPlaygroundAddr
+	manifest := defaultManifest
+	limits := playgroundLimits{Timeout: 30 * time.Second, CPU: 10, Memory: 512}
+	skip := false
+	for index, arg := range args {
+		if skip {
+			skip = false
+			continue
+		}
+		if index >= len(args)-1 {
+			fmt.Printf("Usage:\n\n")
+			fmt.Printf("    go2md playground [-addr <HOST:PORT>] [-m <MANIFEST>] [-cpu <SECONDS>] [-mem <MB>] [-timeout <SECONDS>]\n\n")
+			return 1
+		}
+		value := args[index+1]
+		skip = true
+		var err error
+		switch arg {
+		case "-addr":
+			addr = value
+		case "-m":
+			manifest = value
+		case "-cpu":
+			limits.CPU, err = strconv.Atoi(value)
+		case "-mem":
+			limits.Memory, err = strconv.Atoi(value)
+		case "-timeout":
+			var seconds int
+			seconds, err = strconv.Atoi(value)
+			limits.Timeout = time.Duration(seconds) * time.Second
+		default:
+			fmt.Printf("Unknown flag %s\n", arg)
+			return 1
+		}
+		if err != nil {
+			fmt.Printf("Invalid value for %s: %s\n", arg, value)
+			return 1
+		}
+	}
+
+	chapterFiles, err := readManifest(manifest)
+	if err != nil {
+		fmt.Printf("Unable to read manifest %s\n%v\n", manifest, err)
+		return 1
+	}
+	sections, err := tuiSections(chapterFiles)
+	if err != nil {
+		fmt.Printf("Unable to read chapters\n%v\n", err)
+		return 1
+	}
+
+	// Runs are serialised so that the limits hold for the machine as a whole
+	runSlot := make(chan struct{}, 1)
+	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		playgroundTemplate.Execute(w, playgroundPage{Chapter: "Index", Sections: sections})
+	})
+	http.HandleFunc("/section/", func(w http.ResponseWriter, r *http.Request) {
+		index, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/section/"))
+		if err != nil || index < 0 || index >= len(sections) {
+			http.NotFound(w, r)
+			return
+		}
+		page, err := playgroundSection(sections, index)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if r.Method == http.MethodPost {
+			name := r.FormValue("test")
+			code := strings.ReplaceAll(r.FormValue("code"), "\r\n", "\n")
+			for i := range page.Tests {
+				if page.Tests[i].Name == name {
+					runSlot <- struct{}{}
+					page.Tests[i].Code = code
+					page.Tests[i].Output = playgroundRun(sections[index].File, name, code, limits)
+					<-runSlot
+				}
+			}
+		}
+		playgroundTemplate.Execute(w, page)
+	})
+	fmt.Printf("Serving the playground on http://%s/\n", addr)
+	if err := http.ListenAndServe(addr, nil); err != nil {
+		fmt.Printf("%v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// playgroundSection prepares the page of a section, with the prose
+// gathered into paragraphs and each test's current source.
+func playgroundSection(sections []*tuiSection, index int) (playgroundPage, error) {
+	section := sections[index]
+	page := playgroundPage{Index: index, Chapter: section.Chapter, Title: section.Title, Previous: index - 1, Next: index + 1}
+	if page.Next >= len(sections) {
+		page.Next = -1
+	}
+	paragraph := ""
+	insideCode := false
+	for _, line := range section.Lines {
+		switch {
+		case strings.HasPrefix(line, "```"):
+			insideCode = !insideCode
+		case insideCode || strings.HasPrefix(line, "Source: "):
+		case strings.TrimSpace(line) == "":
+			if paragraph != "" {
+				page.Prose = append(page.Prose, paragraph)
+				paragraph = ""
+			}
+		default:
+			paragraph = strings.TrimSpace(paragraph + " " + line)
+		}
+	}
+	if paragraph != "" {
+		page.Prose = append(page.Prose, paragraph)
+	}
+	for _, name := range section.Tests {
+		_, _, code, err := testSource(section.File, name)
+		if err != nil {
+			return page, err
+		}
+		page.Tests = append(page.Tests, playgroundTest{Name: name, Code: code})
+	}
+	return page, nil
+}
+
+// testSource returns the first and last lines (0-based) of the test
+// function called name in fileName, and its source.
+func testSource(fileName string, name string) (int, int, string, error) {
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return 0, 0, "", err
+	}
+	lines := strings.Split(string(data), "\n")
+	for start, line := range lines {
+		if funcName(line) != name {
+			continue
+		}
+		for end := start; end < len(lines); end++ {
+			if strings.HasPrefix(lines[end], "}") {
+				return start, end, strings.Join(lines[start:end+1], "\n"), nil
+			}
+		}
+	}
+	return 0, 0, "", fmt.Errorf("%s not found in %s", name, fileName)
+}
+
+// playgroundRun replaces test name in a temporary module holding a copy
+// of fileName's package, then builds and runs it within limits.
+func playgroundRun(fileName string, name string, code string, limits playgroundLimits) string {
+	start, end, _, err := testSource(fileName, name)
+	if err != nil {
+		return err.Error()
+	}
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return err.Error()
+	}
+	lines := strings.Split(string(data), "\n")
+	edited := strings.Join(lines[:start], "\n") + "\n" + code + "\n" + strings.Join(lines[end+1:], "\n")
+
+	tmpDir, err := os.MkdirTemp("", "go2md-playground")
+	if err != nil {
+		return err.Error()
+	}
+	defer os.RemoveAll(tmpDir)
+	_, justFile := filepath.Split(fileName)
+	if _, err := instrumentedCopy(fileName, tmpDir, map[string]string{justFile: edited}); err != nil {
+		return err.Error()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), limits.Timeout)
+	defer cancel()
+	var output bytes.Buffer
+	steps := [][]string{
+		{"go", "mod", "init", "playground"},
+		{"go", "test", "-c", "-o", "playground.test", "."},
+		// ulimit takes the CPU time in seconds and the data segment size in KB
+		{"sh", "-c", fmt.Sprintf("ulimit -t %d && ulimit -d %d && exec ./playground.test -test.v -test.run '^%s$'",
+			limits.CPU, limits.Memory*1024, name)},
+	}
+	for _, step := range steps {
+		cmd := exec.CommandContext(ctx, step[0], step[1:]...)
+		cmd.Dir = tmpDir
+		cmd.Env = append(os.Environ(), "GOMAXPROCS=1", fmt.Sprintf("GOMEMLIMIT=%dMiB", limits.Memory))
+		output.Reset()
+		cmd.Stdout = &output
+		cmd.Stderr = &output
+		if err := cmd.Run(); err != nil {
+			if ctx.Err() != nil {
+				return output.String() + fmt.Sprintf("\nTimed out after %v", limits.Timeout)
+			}
+			return output.String() + "\n" + err.Error()
+		}
+	}
+	return output.String()
+}
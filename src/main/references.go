@@ -0,0 +1,410 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Backticked references in prose are checked against the code with
+// go/types. A name resolves when the section the prose belongs to
+// declares or uses it, when it is declared at package level, when an
+// imported package exports it, or when it is predeclared. Selectors are
+// resolved against the package or the type of their operand.
+
+type referenceError struct {
+	Line    int
+	Span    string
+	Name    string
+	Closest string
+}
+
+func refsCommand(args []string) int {
+	if len(args) == 0 {
+		fmt.Printf("Usage:\n\n")
+		fmt.Printf("    go2md refs <file_1> <file_2> ... <file_n>\n\n")
+		return 0
+	}
+	failures := 0
+	for _, fileName := range args {
+		found, err := checkReferences(fileName)
+		if err != nil {
+			fmt.Printf("Unable to check file %s\n%v\n", fileName, err)
+			return 1
+		}
+		for _, e := range found {
+			failures++
+			fmt.Printf("%s:%d: %s\n", fileName, e.Line, e.Message())
+		}
+	}
+	if failures > 0 {
+		return 1
+	}
+	return 0
+}
+
+func (e referenceError) Message() string {
+	message := fmt.Sprintf("%s in `%s` does not resolve", e.Name, e.Span)
+	if e.Closest != "" {
+		message = message + fmt.Sprintf("; did you mean %s?", e.Closest)
+	}
+	return message
+}
+
+// A referenceScope holds what prose in a section may refer to.
+type referenceScope struct {
+	Section  map[string]types.Object
+	Package  *types.Package
+	Imports  map[string]*types.Package
+	Exported map[string]bool
+}
+
+// checkReferences returns the backticked references in the prose of
+// fileName that do not resolve.
+func checkReferences(fileName string) ([]referenceError, error) {
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	lines := strings.Split(string(data), "\n")
+	fset, file, pkg, info, err := typeCheckPackage(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	imports := map[string]*types.Package{}
+	exported := map[string]bool{}
+	for _, spec := range file.Imports {
+		var name *types.PkgName
+		if spec.Name != nil {
+			name, _ = info.Defs[spec.Name].(*types.PkgName)
+		} else {
+			name, _ = info.Implicits[spec].(*types.PkgName)
+		}
+		if name == nil || !name.Imported().Complete() {
+			continue
+		}
+		imports[name.Name()] = name.Imported()
+		for _, member := range name.Imported().Scope().Names() {
+			exported[member] = true
+			if t, ok := name.Imported().Scope().Lookup(member).(*types.TypeName); ok {
+				for _, method := range members(t.Type(), pkg) {
+					exported[method] = true
+				}
+			}
+		}
+	}
+
+	// Objects defined or used on each line of the file
+	lineObjects := map[int][]types.Object{}
+	for _, idents := range []map[*ast.Ident]types.Object{info.Defs, info.Uses} {
+		for ident, obj := range idents {
+			position := fset.Position(ident.Pos())
+			if obj != nil && position.Filename == fileName {
+				lineObjects[position.Line] = append(lineObjects[position.Line], obj)
+			}
+		}
+	}
+
+	found, err := headings(fileName)
+	if err != nil {
+		return nil, err
+	}
+	sectionStart := func(line int) (int, int) {
+		start, end := 1, len(lines)
+		for _, h := range found {
+			if h.Line <= line {
+				start = h.Line
+			} else {
+				end = h.Line - 1
+				break
+			}
+		}
+		return start, end
+	}
+
+	errs := []referenceError{}
+	var scope *referenceScope
+	currentStart := -1
+	for i, line := range lines {
+		if !strings.HasPrefix(line, "// ") || strings.HasPrefix(line, "// Ignore-") {
+			continue
+		}
+		spans := codeSpanPattern.FindAllStringSubmatch(line, -1)
+		if len(spans) == 0 {
+			continue
+		}
+		if start, end := sectionStart(i + 1); start != currentStart {
+			currentStart = start
+			scope = &referenceScope{Section: map[string]types.Object{}, Package: pkg, Imports: imports, Exported: exported}
+			for n := start; n <= end; n++ {
+				for _, obj := range lineObjects[n] {
+					scope.Section[obj.Name()] = obj
+				}
+			}
+		}
+		for _, span := range spans {
+			expr, err := parser.ParseExpr(span[1])
+			if err != nil {
+				// Not Go, such as a syntax template
+				continue
+			}
+			if ident, ok := expr.(*ast.Ident); ok && strings.ToLower(ident.Name) == ident.Name && !strings.ContainsAny(ident.Name, "_0123456789") {
+				// A bare lowercase word is as likely to be English or
+				// another language's keyword
+				continue
+			}
+			for _, e := range scope.unresolved(expr) {
+				e.Line, e.Span = i+1, span[1]
+				errs = append(errs, e)
+			}
+		}
+	}
+	return errs, nil
+}
+
+// A typeCheck is the outcome of type-checking a chapter file's package.
+type typeCheck struct {
+	fset *token.FileSet
+	file *ast.File
+	pkg  *types.Package
+	info *types.Info
+}
+
+// typeChecks keeps the packages type-checked so far by file, as badges
+// and deprecation warnings both need them.
+var typeChecks = map[string]*typeCheck{}
+
+// typeCheckPackage type-checks fileName along with the other files in
+// its directory that declare the same package. Type errors, such as
+// those caused by imports that cannot be resolved, are tolerated.
+func typeCheckPackage(fileName string) (*token.FileSet, *ast.File, *types.Package, *types.Info, error) {
+	if c, found := typeChecks[fileName]; found {
+		return c.fset, c.file, c.pkg, c.info, nil
+	}
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, fileName, nil, parser.ParseComments)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	astFiles := []*ast.File{file}
+	dir, justFile := filepath.Split(fileName)
+	siblings, _ := filepath.Glob(filepath.Join(dir, "*.go"))
+	sort.Strings(siblings)
+	for _, sibling := range siblings {
+		if _, name := filepath.Split(sibling); name == justFile {
+			continue
+		}
+		other, err := parser.ParseFile(fset, sibling, nil, 0)
+		if err != nil || other.Name.Name != file.Name.Name {
+			continue
+		}
+		astFiles = append(astFiles, other)
+	}
+	if sourceImporter == nil {
+		sourceImporter = newSourceImporter(fset)
+	}
+	info := &types.Info{
+		Defs:      map[*ast.Ident]types.Object{},
+		Uses:      map[*ast.Ident]types.Object{},
+		Implicits: map[ast.Node]types.Object{},
+		Types:     map[ast.Expr]types.TypeAndValue{},
+	}
+	config := types.Config{Importer: sourceImporter, Error: func(err error) {}}
+	pkg, _ := config.Check(file.Name.Name, fset, astFiles, info)
+	typeChecks[fileName] = &typeCheck{fset: fset, file: file, pkg: pkg, info: info}
+	return fset, file, pkg, info, nil
+}
+
+// lookup resolves a bare name.
+func (scope *referenceScope) lookup(name string) (types.Object, bool) {
+	if obj, found := scope.Section[name]; found {
+		return obj, true
+	}
+	if obj := scope.Package.Scope().Lookup(name); obj != nil {
+		return obj, true
+	}
+	if pkg, found := scope.Imports[name]; found {
+		return types.NewPkgName(token.NoPos, scope.Package, name, pkg), true
+	}
+	if obj := types.Universe.Lookup(name); obj != nil {
+		return obj, true
+	}
+	// A field or method of a package level type
+	for _, typeName := range scope.Package.Scope().Names() {
+		if t, ok := scope.Package.Scope().Lookup(typeName).(*types.TypeName); ok {
+			if obj, _, _ := types.LookupFieldOrMethod(t.Type(), true, scope.Package, name); obj != nil {
+				return obj, true
+			}
+		}
+	}
+	return nil, scope.Exported[name]
+}
+
+// candidates returns the names a bare name could have been meant as.
+func (scope *referenceScope) candidates() []string {
+	names := []string{}
+	for name := range scope.Section {
+		names = append(names, name)
+	}
+	names = append(names, scope.Package.Scope().Names()...)
+	for name := range scope.Imports {
+		names = append(names, name)
+	}
+	return names
+}
+
+// unresolved returns the names in expr that do not resolve. Operands,
+// such as the arguments of a call or the sides of a comparison, that
+// resolve to nothing and are no likely typo either are placeholders, as
+// in `pending == 0` or `close(channel)`.
+func (scope *referenceScope) unresolved(expr ast.Expr) []referenceError {
+	errs := []referenceError{}
+	var resolve func(expr ast.Expr, operand bool) types.Object
+	resolve = func(expr ast.Expr, operand bool) types.Object {
+		switch e := expr.(type) {
+		case *ast.Ident:
+			if e.Name == "_" || strings.ToUpper(e.Name) == e.Name {
+				// All-caps names are placeholders, such as N or EXPR
+				return nil
+			}
+			obj, found := scope.lookup(e.Name)
+			if !found {
+				closest := closestName(e.Name, scope.candidates())
+				if !operand || closest != "" {
+					errs = append(errs, referenceError{Name: e.Name, Closest: closest})
+				}
+			}
+			return obj
+		case *ast.SelectorExpr:
+			x := resolve(e.X, false)
+			if x == nil {
+				return nil
+			}
+			if pkgName, ok := x.(*types.PkgName); ok {
+				obj := pkgName.Imported().Scope().Lookup(e.Sel.Name)
+				if obj == nil {
+					errs = append(errs, referenceError{Name: pkgName.Name() + "." + e.Sel.Name, Closest: closestName(e.Sel.Name, pkgName.Imported().Scope().Names())})
+				}
+				return obj
+			}
+			if x.Type() == nil {
+				return nil
+			}
+			obj, _, _ := types.LookupFieldOrMethod(x.Type(), true, scope.Package, e.Sel.Name)
+			if obj == nil {
+				errs = append(errs, referenceError{Name: e.Sel.Name, Closest: closestName(e.Sel.Name, members(x.Type(), scope.Package))})
+			}
+			return obj
+		case *ast.CallExpr:
+			fun := resolve(e.Fun, false)
+			for _, arg := range e.Args {
+				resolve(arg, true)
+			}
+			if signature, ok := typeOf(fun).(*types.Signature); ok && signature.Results().Len() == 1 {
+				return types.NewVar(token.NoPos, scope.Package, "", signature.Results().At(0).Type())
+			}
+		case *ast.StarExpr:
+			resolve(e.X, operand)
+		case *ast.ParenExpr:
+			return resolve(e.X, operand)
+		case *ast.UnaryExpr:
+			resolve(e.X, true)
+		case *ast.BinaryExpr:
+			resolve(e.X, true)
+			resolve(e.Y, true)
+		case *ast.IndexExpr:
+			resolve(e.X, operand)
+			resolve(e.Index, true)
+		case *ast.TypeAssertExpr:
+			resolve(e.X, operand)
+			if e.Type != nil {
+				resolve(e.Type, false)
+			}
+		case *ast.CompositeLit:
+			if e.Type != nil {
+				resolve(e.Type, false)
+			}
+		}
+		return nil
+	}
+	resolve(expr, false)
+	return errs
+}
+
+func typeOf(obj types.Object) types.Type {
+	if obj == nil {
+		return nil
+	}
+	return obj.Type().Underlying()
+}
+
+// members returns the names of the fields and methods of t that are
+// accessible from pkg.
+func members(t types.Type, pkg *types.Package) []string {
+	names := []string{}
+	accessible := func(obj types.Object) {
+		if obj.Exported() || obj.Pkg() == pkg {
+			names = append(names, obj.Name())
+		}
+	}
+	methods := types.NewMethodSet(types.NewPointer(t))
+	for i := 0; i < methods.Len(); i++ {
+		accessible(methods.At(i).Obj())
+	}
+	if pointer, ok := t.Underlying().(*types.Pointer); ok {
+		t = pointer.Elem()
+	}
+	if structType, ok := t.Underlying().(*types.Struct); ok {
+		for i := 0; i < structType.NumFields(); i++ {
+			accessible(structType.Field(i))
+		}
+	}
+	return names
+}
+
+// closestName returns the candidate nearest to name by edit distance,
+// ignoring case, if it is near enough to be a likely typo.
+func closestName(name string, candidates []string) string {
+	sort.Strings(candidates)
+	closest, best := "", 2
+	if len(name) <= 4 {
+		best = 1
+	}
+	for _, candidate := range candidates {
+		if candidate == name {
+			continue
+		}
+		if distance := editDistance(strings.ToLower(name), strings.ToLower(candidate)); distance < best || (distance == best && closest == "") {
+			closest, best = candidate, distance
+		}
+	}
+	return closest
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a string, b string) int {
+	previous := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current := make([]int, len(b)+1)
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous = current
+	}
+	return previous[len(b)]
+}
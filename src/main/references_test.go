@@ -0,0 +1,49 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const referencesChapter = `package waiting
+
+import (
+	"fmt"
+	"testing"
+)
+
+// ## Waiting
+// ` + "`Wait()` blocks until `pending == 0`, then `close(channel)`." + `
+// The result is printed with ` + "`fmt.Sprintff()` by `report(done)`." + `
+// ` + "`foodChannel`" + ` is gone.
+func Test_Waiting(t *testing.T) {
+	done := 0
+	report := func(n int) string { return fmt.Sprintf("%d", n) }
+	report(done)
+}
+`
+
+func Test_CheckReferences(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "waiting_test.go")
+	if err := os.WriteFile(fileName, []byte(referencesChapter), 0644); err != nil {
+		t.Fatal(err)
+	}
+	found, err := checkReferences(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	messages := []string{}
+	for _, e := range found {
+		messages = append(messages, e.Message())
+	}
+	want := []string{
+		"Wait in `Wait()` does not resolve",
+		"fmt.Sprintff in `fmt.Sprintff()` does not resolve; did you mean Sprintf?",
+		"foodChannel in `foodChannel` does not resolve",
+	}
+	if strings.Join(messages, "\n") != strings.Join(want, "\n") {
+		t.Errorf("checkReferences found\n%s\nwant\n%s", strings.Join(messages, "\n"), strings.Join(want, "\n"))
+	}
+}
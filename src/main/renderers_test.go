@@ -0,0 +1,52 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files")
+
+// goldenDocument returns the document of testdata/golden.go along with
+// its Go version badges, its deprecation warnings, its callouts and the
+// output blocks that only running or type checking would add.
+func goldenDocument() *document {
+	srcRoot = "https://example.com/"
+	doc := file2doc("testdata/golden.go")
+	if err := applyVersions(doc); err != nil {
+		panic(err)
+	}
+	if _, err := applyDeprecations(doc); err != nil {
+		panic(err)
+	}
+	applyCallouts(doc)
+	doc.Blocks = append(doc.Blocks,
+		block{Kind: outputBlock, Lines: []string{"golden.go:1:1: undefined: gold"}},
+		block{Kind: outputBlock, Label: "Stdout", Lines: []string{"golden", ""}},
+	)
+	return doc
+}
+
+func Test_Renderers(t *testing.T) {
+	for format, goldenFile := range map[string]string{
+		"markdown": "testdata/golden.md",
+		"asciidoc": "testdata/golden.adoc",
+		"rst":      "testdata/golden.rst",
+	} {
+		rendered := renderers[format](goldenDocument())
+		if *update {
+			if err := os.WriteFile(goldenFile, []byte(rendered), 0644); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		expected, err := os.ReadFile(goldenFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rendered != string(expected) {
+			t.Errorf("%s output differs from %s:\n%s", format, goldenFile, rendered)
+		}
+	}
+}
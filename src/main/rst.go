@@ -0,0 +1,154 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// The reStructuredText output targets Sphinx. Each heading is preceded by
+// a label named after its pandoc identifier, so that links between
+// chapters become :ref: roles, and compiler messages are shown as warning
+// admonitions.
+
+// rstUnderlines gives the heading underline character of each level.
+var rstUnderlines = []string{"=", "-", "~", "^", "\""}
+
+// renderRst renders a document as reStructuredText.
+func renderRst(doc *document) string {
+	rst := ""
+	ids := headingIDs(doc.Blocks, map[string]int{})
+	for i, b := range doc.Blocks {
+		switch b.Kind {
+		case headingBlock:
+			level := b.Level - 1
+			if level >= len(rstUnderlines) {
+				level = len(rstUnderlines) - 1
+			}
+			title := rstInline(b.Lines[0])
+			rst = rst + fmt.Sprintf("\n.. _%s:\n\n%s\n%s\n", ids[i], title, strings.Repeat(rstUnderlines[level], utf8.RuneCountInString(title)))
+		case proseBlock:
+			rst = rst + rstProse(b.Lines)
+		case codeBlock:
+			code := rstCodeBlock("go", "", b.Lines)
+			if b.Label != "" {
+				code = strings.Replace(code, ".. code-block:: go\n", ".. code-block:: go\n   :caption: "+rstEscape(b.Label)+"\n   :name: "+b.Label+"\n", 1)
+			}
+			rst = rst + code
+		case outputBlock:
+			if b.Label == "" {
+				rst = rst + "\n.. warning::\n" + rstCodeBlock("text", "   ", b.Lines)
+				continue
+			}
+			rst = rst + "\n**" + rstEscape(b.Label) + ":**\n" + rstCodeBlock("text", "", b.Lines)
+		case badgeBlock:
+			rst = rst + "\n.. container:: go-version\n\n   " + rstEscape(b.Lines[0]) + "\n"
+		case admonitionBlock:
+			rst = rst + "\n.. " + strings.ToLower(b.Label) + "::\n" + rstIndent(rstProse(b.Lines), "   ")
+		case calloutBlock:
+			// Sphinx has no callouts: the list is numbered like the markers
+			rst = rst + "\n"
+			for _, line := range b.Lines {
+				marker, text, _ := strings.Cut(line, " ")
+				n, _ := calloutNumber(marker)
+				rst = rst + fmt.Sprintf("%d. %s\n", n, rstEscape(text))
+			}
+		case diagramBlock:
+			rst = rst + "\n.. mermaid::\n\n" + rstIndent(strings.Join(b.Lines, "\n"), "   ") + "\n"
+		case benchmarkBlock:
+			rst = rst + "\n.. list-table:: " + rstEscape(b.Label) + "\n   :header-rows: 1\n\n"
+			for _, line := range b.Lines {
+				for i, text := range strings.Split(line, "\t") {
+					prefix := "     - "
+					if i == 0 {
+						prefix = "   * - "
+					}
+					rst = rst + prefix + rstEscape(text) + "\n"
+				}
+			}
+		case sourceBlock:
+			_, justFile := filepath.Split(b.File)
+			rst = rst + fmt.Sprintf("\nSource: `%s <%s%s#L%d>`__\n", rstEscape(justFile), srcRoot, b.File, b.Line)
+		}
+	}
+	return rst
+}
+
+// rstCodeBlock returns a code-block directive holding lines, itself
+// indented by indent.
+func rstCodeBlock(language string, indent string, lines []string) string {
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	rst := "\n" + indent + ".. code-block:: " + language + "\n\n"
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			rst = rst + "\n"
+			continue
+		}
+		rst = rst + indent + "   " + line + "\n"
+	}
+	return rst
+}
+
+// rstIndent indents the non-blank lines of rst by indent.
+func rstIndent(rst string, indent string) string {
+	lines := strings.Split(rst, "\n")
+	for i, line := range lines {
+		if strings.TrimSpace(line) != "" {
+			lines[i] = indent + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func rstProse(lines []string) string {
+	rst := ""
+	parts := splitProse(lines)
+	for i, part := range parts {
+		switch part.Kind {
+		case paragraphPart:
+			rst = rst + "\n"
+			for _, line := range part.Lines {
+				rst = rst + rstInline(line) + "\n"
+			}
+		case itemPart:
+			if i == 0 || parts[i-1].Kind != itemPart {
+				rst = rst + "\n"
+			}
+			rst = rst + "* " + rstInline(strings.Join(part.Lines, " ")) + "\n"
+		case fencePart:
+			rst = rst + rstCodeBlock("text", "", part.Lines)
+		}
+	}
+	return rst
+}
+
+var rstInlineRenderer = inlineRenderer{
+	Code:   func(code string) string { return "``" + code + "``" },
+	Emph:   func(text string) string { return "*" + text + "*" },
+	Strong: func(text string) string { return "**" + text + "**" },
+	Link: func(label string, target string) string {
+		if strings.HasPrefix(target, "#") {
+			return ":ref:`" + label + " <" + target[1:] + ">`"
+		}
+		// Anonymous, so that the same label may link to different targets
+		return "`" + label + " <" + target + ">`__"
+	},
+	Text: rstEscape,
+}
+
+func rstInline(text string) string {
+	return renderInline(text, rstInlineRenderer)
+}
+
+func rstEscape(text string) string {
+	return strings.NewReplacer(
+		"\\", "\\\\",
+		"*", "\\*",
+		"`", "\\`",
+		"_", "\\_",
+		"|", "\\|",
+	).Replace(text)
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A //go2md:run directive placed right before a function runs it at
+// conversion time and embeds its output under the snippet that renders
+// it. Before `func main()` the package's program is built and run;
+// before `func Test_X(...)` the package's tests are built and only Test_X
+// is run. Standard input is optionally given as stdin="<quoted string>".
+// A snippet still building or running after runTimeout is stopped, and
+// what it printed so far is shown along with a timeout notice.
+const runDirective = "//go2md:run"
+
+const runTimeout = 60 * time.Second
+
+type runRequest struct {
+	Stdin  string
+	Target string
+}
+
+type runResult struct {
+	Stdout   string
+	Stderr   string
+	Exit     int
+	Compiler string
+	TimedOut bool
+}
+
+// parseRunDirective parses the arguments of a //go2md:run line.
+func parseRunDirective(line string) (*runRequest, error) {
+	args := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), runDirective))
+	request := &runRequest{}
+	if args == "" {
+		return request, nil
+	}
+	if !strings.HasPrefix(args, "stdin=") {
+		return nil, fmt.Errorf("unknown argument %s", args)
+	}
+	stdin, err := strconv.Unquote(strings.TrimPrefix(args, "stdin="))
+	if err != nil {
+		return nil, fmt.Errorf("stdin must be a quoted string: %v", err)
+	}
+	request.Stdin = stdin
+	return request, nil
+}
+
+// funcName extracts the function name from a line such as
+// "func Test_X(t *testing.T) {". Methods are not supported.
+func funcName(line string) string {
+	if !strings.HasPrefix(line, "func ") {
+		return ""
+	}
+	name := strings.TrimPrefix(line, "func ")
+	if end := strings.Index(name, "("); end > 0 {
+		return name[:end]
+	}
+	return ""
+}
+
+// packageFiles returns the .go files next to fileName that declare the
+// same package, either only test files or only non-test files.
+func packageFiles(fileName string, tests bool) []string {
+	dir, _ := filepath.Split(fileName)
+	pkg := packageName(fileName)
+	matches, _ := filepath.Glob(filepath.Join(dir, "*.go"))
+	found := []string{}
+	for _, match := range matches {
+		if strings.HasSuffix(match, "_test.go") != tests {
+			continue
+		}
+		if packageName(match) == pkg {
+			_, justFile := filepath.Split(match)
+			found = append(found, justFile)
+		}
+	}
+	return found
+}
+
+// packageName returns the name in the package clause of fileName.
+func packageName(fileName string) string {
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return ""
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.HasPrefix(line, "package ") {
+			return strings.TrimSpace(strings.TrimPrefix(line, "package "))
+		}
+	}
+	return ""
+}
+
+// runSnippet builds the program or test binary backing request and runs
+// it. A build failure is not an error: its diagnostic is the result.
+func runSnippet(fileName string, request *runRequest) (*runResult, error) {
+	tmpDir, err := os.MkdirTemp("", "go2md-run")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	dir, _ := filepath.Split(fileName)
+	binary := filepath.Join(tmpDir, "program")
+	var build, run []string
+	if request.Target == "main" {
+		build = append([]string{"build", "-o", binary}, packageFiles(fileName, false)...)
+		run = []string{}
+	} else {
+		build = append([]string{"test", "-c", "-o", binary}, packageFiles(fileName, true)...)
+		run = []string{"-test.v", "-test.run", "^" + request.Target + "$"}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), runTimeout)
+	defer cancel()
+
+	var buildOutput bytes.Buffer
+	buildCmd := exec.CommandContext(ctx, "go", build...)
+	buildCmd.Dir = dir
+	buildCmd.Stdout = &buildOutput
+	buildCmd.Stderr = &buildOutput
+	if err := buildCmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return &runResult{TimedOut: true}, nil
+		}
+		if _, ok := err.(*exec.ExitError); !ok {
+			return nil, err
+		}
+		return &runResult{Compiler: buildOutput.String(), Exit: buildCmd.ProcessState.ExitCode()}, nil
+	}
+
+	var stdout, stderr bytes.Buffer
+	runCmd := exec.CommandContext(ctx, binary, run...)
+	runCmd.Dir = dir
+	runCmd.Stdin = strings.NewReader(request.Stdin)
+	runCmd.Stdout = &stdout
+	runCmd.Stderr = &stderr
+	if err := runCmd.Run(); err != nil {
+		if _, ok := err.(*exec.ExitError); !ok {
+			return nil, err
+		}
+	}
+	return &runResult{
+		Stdout:   stdout.String(),
+		Stderr:   stderr.String(),
+		Exit:     runCmd.ProcessState.ExitCode(),
+		TimedOut: ctx.Err() == context.DeadlineExceeded,
+	}, nil
+}
+
+// resultBlocks returns the blocks showing a run result.
+func resultBlocks(result *runResult) []block {
+	textBlock := func(label string, text string) block {
+		return block{Kind: outputBlock, Label: label, Lines: strings.Split(strings.TrimRight(text, "\n"), "\n")}
+	}
+	if result.Compiler != "" {
+		return []block{textBlock("Compiler output", result.Compiler)}
+	}
+	blocks := []block{}
+	if result.Stdout != "" {
+		blocks = append(blocks, textBlock("Stdout", result.Stdout))
+	}
+	if result.Stderr != "" {
+		blocks = append(blocks, textBlock("Stderr", result.Stderr))
+	}
+	if result.TimedOut {
+		// What was printed so far is shown, but there is no exit status
+		return append(blocks, block{Kind: outputBlock, Lines: []string{fmt.Sprintf("timed out after %ds", int(runTimeout.Seconds()))}})
+	}
+	return append(blocks, block{Kind: proseBlock, Lines: []string{"", fmt.Sprintf("Exit status: %d", result.Exit)}})
+}
@@ -0,0 +1,13 @@
+package main
+
+import "testing"
+
+func Test_ResultBlocksTimeout(t *testing.T) {
+	blocks := resultBlocks(&runResult{Stdout: "tick\ntick\n", Exit: -1, TimedOut: true})
+	if len(blocks) != 2 || blocks[0].Label != "Stdout" {
+		t.Fatalf("resultBlocks = %+v; want the output so far and a timeout notice", blocks)
+	}
+	if notice := blocks[1]; notice.Kind != outputBlock || len(notice.Lines) != 1 || notice.Lines[0] != "timed out after 60s" {
+		t.Errorf("timeout notice = %+v; want %q", notice, "timed out after 60s")
+	}
+}
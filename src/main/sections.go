@@ -0,0 +1,103 @@
+package main
+
+import (
+	"os"
+	"strings"
+)
+
+// A heading is a markdown heading written as a comment, such as
+// "// ## Select", found on Line of a chapter file.
+type heading struct {
+	Line  int
+	Level int
+	Title string
+}
+
+// parseHeading returns the heading in line, if there is one.
+func parseHeading(line string) (heading, bool) {
+	if !strings.HasPrefix(line, "// #") {
+		return heading{}, false
+	}
+	text := strings.TrimPrefix(line, "// ")
+	level := len(text) - len(strings.TrimLeft(text, "#"))
+	title := strings.TrimSpace(text[level:])
+	if title == "" || text[level] != ' ' {
+		return heading{}, false
+	}
+	return heading{Level: level, Title: title}, true
+}
+
+// admonitionKinds are the labels that start an admonition paragraph.
+var admonitionKinds = []string{"Note", "Warning", "Tip"}
+
+// parseAdmonition returns the kind and the rest of the first line of an
+// admonition paragraph such as "// Note: text", if line starts one.
+func parseAdmonition(line string) (string, string, bool) {
+	for _, kind := range admonitionKinds {
+		if text, found := strings.CutPrefix(line, "// "+kind+":"); found && (text == "" || text[0] == ' ') {
+			return kind, strings.TrimSpace(text), true
+		}
+	}
+	return "", "", false
+}
+
+// headings returns the headings of fileName in order of appearance.
+func headings(fileName string) ([]heading, error) {
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	found := []heading{}
+	for i, line := range strings.Split(string(data), "\n") {
+		if h, ok := parseHeading(line); ok {
+			h.Line = i + 1
+			found = append(found, h)
+		}
+	}
+	return found, nil
+}
+
+// A location tells the chapter and section a line belongs to.
+type location struct {
+	Chapter string
+	Section string
+}
+
+// locations maps each line of fileName to its chapter and section. A file
+// without a chapter heading continues the chapter of the previous file,
+// which is given as chapter.
+func locations(fileName string, chapter string) (map[int]location, string, error) {
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, chapter, err
+	}
+	found := map[int]location{}
+	section := ""
+	for i, line := range strings.Split(string(data), "\n") {
+		if h, ok := parseHeading(line); ok {
+			if h.Level == 1 {
+				chapter = h.Title
+				section = ""
+			} else if h.Level == 2 {
+				section = h.Title
+			}
+		}
+		found[i+1] = location{Chapter: chapter, Section: section}
+	}
+	return found, chapter, nil
+}
+
+// readManifest returns the chapter files listed in manifest, one per line.
+func readManifest(manifest string) ([]string, error) {
+	data, err := os.ReadFile(manifest)
+	if err != nil {
+		return nil, err
+	}
+	chapterFiles := []string{}
+	for _, line := range strings.Split(string(data), "\n") {
+		if fileName := strings.TrimSpace(line); fileName != "" {
+			chapterFiles = append(chapterFiles, fileName)
+		}
+	}
+	return chapterFiles, nil
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// The site export writes a markdown file per chapter, with its own front
+// matter, into a Hugo or Jekyll content directory. Every chapter is
+// served at /<slug>/, so links to headings in other chapters are rewritten
+// to point there. The header's front matter and introduction become the
+// index page.
+
+const defaultSiteDir = "site"
+
+var siteGenerators = map[string]string{
+	"hugo":   "_index.md",
+	"jekyll": "index.md",
+}
+
+var headingLinkPattern = regexp.MustCompile(`\]\(#([^)]+)\)`)
+
+func siteCommand(args []string) int {
+	outDir := defaultSiteDir
+	manifest := defaultManifest
+	headerFile := ""
+	generator := "hugo"
+	skip := false
+	for index, arg := range args {
+		if skip {
+			skip = false
+			continue
+		}
+		if index >= len(args)-1 {
+			fmt.Printf("Usage:\n\n")
+			fmt.Printf("    go2md site [-o <DIR>] [-m <MANIFEST>] [-header <HEADER_FILE>] [-for <hugo|jekyll>]\n\n")
+			return 1
+		}
+		value := args[index+1]
+		skip = true
+		switch arg {
+		case "-o":
+			outDir = value
+		case "-m":
+			manifest = value
+		case "-header":
+			headerFile = value
+		case "-for":
+			generator = value
+		default:
+			fmt.Printf("Unknown flag %s\n", arg)
+			return 1
+		}
+	}
+	indexFile, found := siteGenerators[generator]
+	if !found {
+		fmt.Printf("Unknown site generator %s\n", generator)
+		return 1
+	}
+
+	chapterFiles, err := readManifest(manifest)
+	if err != nil {
+		fmt.Printf("Unable to read manifest %s\n%v\n", manifest, err)
+		return 1
+	}
+	docs := []*document{}
+	for _, fileName := range chapterFiles {
+		docs = append(docs, file2doc(fileName))
+	}
+	chapters := splitChapters(docs)
+
+	// Slugs are the chapter heading identifiers; pages maps every heading
+	// identifier to the slug of its chapter.
+	slugs := []string{}
+	pages := map[string]string{}
+	for _, chapter := range chapters {
+		slug := ""
+		for _, b := range chapter.Blocks {
+			if b.Kind != headingBlock {
+				continue
+			}
+			id := headingID(b.Lines[0])
+			if slug == "" {
+				slug = id
+			}
+			if _, found := pages[id]; !found {
+				pages[id] = slug
+			}
+		}
+		if slug == "" {
+			slug = fmt.Sprintf("chapter-%d", len(slugs)+1)
+		}
+		slugs = append(slugs, slug)
+	}
+
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		fmt.Printf("Unable to create %s\n%v\n", outDir, err)
+		return 1
+	}
+	if headerFile != "" {
+		h, err := readHeader(headerFile)
+		if err != nil {
+			fmt.Printf("Unable to read header %s\n%v\n", headerFile, err)
+			return 1
+		}
+		fields := [][2]string{}
+		for _, key := range h.Keys {
+			fields = append(fields, [2]string{key, h.Fields[key]})
+		}
+		content := frontMatter(fields) + siteLinks(strings.Split(h.Body, "\n"), pages, "", "")
+		if err := os.WriteFile(filepath.Join(outDir, indexFile), []byte(content), 0644); err != nil {
+			fmt.Printf("Unable to write %s\n%v\n", indexFile, err)
+			return 1
+		}
+	}
+	for i, chapter := range chapters {
+		title := slugs[i]
+		if len(chapter.Blocks) > 0 && chapter.Blocks[0].Kind == headingBlock {
+			title = chapter.Blocks[0].Lines[0]
+			// The title is shown by the site's layout
+			chapter.Blocks = chapter.Blocks[1:]
+		}
+		for j, b := range chapter.Blocks {
+			if b.Kind == proseBlock {
+				chapter.Blocks[j].Lines = strings.Split(siteLinks(b.Lines, pages, slugs[i], "../"), "\n")
+			}
+		}
+		fields := [][2]string{
+			{"title", title},
+			{"weight", strconv.Itoa(i + 1)},
+			{"slug", slugs[i]},
+			{"description", chapterDescription(chapter)},
+		}
+		if generator == "jekyll" {
+			fields = append(fields, [2]string{"layout", "page"}, [2]string{"permalink", "/" + slugs[i] + "/"})
+		}
+		content := frontMatter(fields) + strings.TrimLeft(renderMarkdown(chapter), "\n")
+		fileName := filepath.Join(outDir, slugs[i]+".md")
+		if err := os.WriteFile(fileName, []byte(content), 0644); err != nil {
+			fmt.Printf("Unable to write %s\n%v\n", fileName, err)
+			return 1
+		}
+		fmt.Printf("%s\n", fileName)
+	}
+	return 0
+}
+
+// frontMatter returns YAML front matter with fields in order, quoting
+// every value but integers.
+func frontMatter(fields [][2]string) string {
+	yaml := "---\n"
+	for _, field := range fields {
+		value := strconv.Quote(field[1])
+		if _, err := strconv.Atoi(field[1]); err == nil {
+			value = field[1]
+		}
+		yaml = yaml + field[0] + ": " + value + "\n"
+	}
+	return yaml + "---\n"
+}
+
+// siteLinks rewrites links to headings that are not in the chapter with
+// the given slug so that they point to the chapter holding them, relative
+// to base.
+func siteLinks(lines []string, pages map[string]string, slug string, base string) string {
+	text := strings.Join(lines, "\n")
+	return headingLinkPattern.ReplaceAllStringFunc(text, func(link string) string {
+		id := headingLinkPattern.FindStringSubmatch(link)[1]
+		page, found := pages[id]
+		if !found || page == slug {
+			return link
+		}
+		if id == page {
+			return "](" + base + page + "/)"
+		}
+		return "](" + base + page + "/#" + id + ")"
+	})
+}
+
+// chapterDescription returns the first paragraph of a chapter as plain
+// text.
+func chapterDescription(chapter *document) string {
+	plain := inlineRenderer{
+		Code:   func(code string) string { return code },
+		Emph:   func(text string) string { return text },
+		Strong: func(text string) string { return text },
+		Link:   func(label string, target string) string { return label },
+		Text:   func(text string) string { return text },
+	}
+	for _, b := range chapter.Blocks {
+		if b.Kind != proseBlock {
+			continue
+		}
+		for _, part := range splitProse(b.Lines) {
+			if part.Kind == paragraphPart {
+				return renderInline(strings.Join(part.Lines, " "), plain)
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Assertions are rendered with one of several symbol sets, chosen with
+// -symbols: unicode (a ⇔ b), ascii (a == b) or comment (b // => a), for
+// terminals and fonts that lack the arrows. The legend in the header is
+// rewritten to match.
+
+type symbolSet struct {
+	Equal    string
+	NotEqual string
+	// Comment puts the actual value first and the expected one in a
+	// comment after the symbol
+	Comment bool
+}
+
+var symbolSets = map[string]symbolSet{
+	"unicode": {Equal: "⇔", NotEqual: "⇎"},
+	"ascii":   {Equal: "==", NotEqual: "!="},
+	"comment": {Equal: "// =>", NotEqual: "// !=", Comment: true},
+}
+
+var symbols = symbolSets["unicode"]
+
+// equality renders the assertion that expected and actual are equal, or
+// not. Actual may end with a comment of its own.
+func (s symbolSet) equality(expected string, actual string, equals bool) string {
+	symbol := s.Equal
+	if !equals {
+		symbol = s.NotEqual
+	}
+	if !s.Comment {
+		return fmt.Sprintf("%s %s %s", expected, symbol, actual)
+	}
+	// The indentation of the line stays in front
+	indent := expected[:len(expected)-len(strings.TrimLeft(expected, " \t"))]
+	expected = expected[len(indent):]
+	raw := false
+	code, comment, found := trailingComment(actual, &raw)
+	if !found {
+		return fmt.Sprintf("%s%s %s %s", indent, actual, symbol, expected)
+	}
+	return fmt.Sprintf("%s%s %s %s; %s", indent, code, symbol, expected, comment)
+}
+
+// legendPattern matches the code spans of the header that show how
+// assertions are rendered, such as `a ⇔ b`.
+var legendPattern = regexp.MustCompile("`([^`]*?) (⇔|⇎) ([^`]*)`")
+
+// legend rewrites the assertions shown in markdown with the symbol set.
+func (s symbolSet) legend(markdown string) string {
+	return legendPattern.ReplaceAllStringFunc(markdown, func(span string) string {
+		parts := legendPattern.FindStringSubmatch(span)
+		return "`" + s.equality(parts[1], parts[3], parts[2] == "⇔") + "`"
+	})
+}
@@ -0,0 +1,22 @@
+package main
+
+import "testing"
+
+func Test_Symbols(t *testing.T) {
+	line := "\tassert.Equal(t, 255, 0xFF) // Hex"
+	for name, expected := range map[string]string{
+		"unicode": "\t255 ⇔ 0xFF // Hex",
+		"ascii":   "\t255 == 0xFF // Hex",
+		"comment": "\t0xFF // => 255; Hex",
+	} {
+		symbols = symbolSets[name]
+		if rendered := assert2equality(line); rendered != expected {
+			t.Errorf("%s: assert2equality gave %q; want %q", name, rendered, expected)
+		}
+	}
+	symbols = symbolSets["comment"]
+	if legend := symbols.legend("`a ⇎ b`"); legend != "`b // != a`" {
+		t.Errorf("legend gave %q", legend)
+	}
+	symbols = symbolSets["unicode"]
+}
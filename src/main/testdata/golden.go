@@ -0,0 +1,45 @@
+// # Golden Chapter
+// Prose with _emphasis_, **strong emphasis**, `code`, a [link](https://go.dev/)
+// and a [cross reference](#golden-section). Characters such as *, _, #, |, +,
+// [brackets], <angles> and \ are taken literally.
+//
+// Ignore-On
+package golden
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Ignore-Off
+// ## Golden Section
+// A list:
+//
+// * First item
+// * Second item
+//   continued
+//
+// ```
+// fenced text
+// ```
+//go2md:tests caption
+var golden = "golden"
+var mode = 0o644 // rw-r--r--
+var discard = ioutil.Discard // drops all writes
+
+func Test_Golden(t *testing.T) {
+	// Assertions
+	assert.Equal(t, "golden", golden)
+	assert.NotEqual(t, "silver", golden)
+}
+
+// ## Golden Section
+// A repeated heading.
+//
+// Tip: An admonition runs to the end
+// of its paragraph.
+//
+// Note:
+// Its text may start on the next line.
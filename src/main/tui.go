@@ -0,0 +1,409 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"go/scanner"
+	"go/token"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// The tutorial browser pages through the sections of the chapters listed
+// in the manifest. It only relies on ANSI escape sequences and on stty to
+// read single key presses, so that no third party packages are needed.
+
+const defaultManifest = "files"
+
+const (
+	ansiReset   = "\x1b[0m"
+	ansiBold    = "\x1b[1m"
+	ansiDim     = "\x1b[2m"
+	ansiRed     = "\x1b[31m"
+	ansiGreen   = "\x1b[32m"
+	ansiYellow  = "\x1b[33m"
+	ansiBlue    = "\x1b[34m"
+	ansiMagenta = "\x1b[35m"
+	ansiCyan    = "\x1b[36m"
+	ansiClear   = "\x1b[2J\x1b[H"
+)
+
+type tuiSection struct {
+	Chapter string
+	Title   string
+	File    string
+	Lines   []string
+	Tests   []string
+	Result  []string
+}
+
+func tuiCommand(args []string) int {
+	manifest := defaultManifest
+	if len(args) > 0 {
+		manifest = args[0]
+	}
+	chapterFiles, err := readManifest(manifest)
+	if err != nil {
+		fmt.Printf("Unable to read manifest %s\n%v\n", manifest, err)
+		return 1
+	}
+	sections, err := tuiSections(chapterFiles)
+	if err != nil {
+		fmt.Printf("Unable to read chapters\n%v\n", err)
+		return 1
+	}
+	if len(sections) == 0 {
+		fmt.Printf("No sections found in %s\n", manifest)
+		return 1
+	}
+
+	restore, err := keyPressMode()
+	if err != nil {
+		fmt.Printf("Unable to set up the terminal\n%v\n", err)
+		return 1
+	}
+	defer func() {
+		restore()
+		if r := recover(); r != nil {
+			panic(r)
+		}
+	}()
+
+	keys := bufio.NewReader(os.Stdin)
+	current, offset := 0, 0
+	for {
+		rows, _ := terminalSize()
+		offset = tuiDraw(sections, current, offset, rows)
+		key, err := readKey(keys)
+		if err != nil {
+			return 0
+		}
+		switch key {
+		case "q":
+			os.Stdout.WriteString(ansiClear)
+			return 0
+		case "n", "right":
+			if current < len(sections)-1 {
+				current, offset = current+1, 0
+			}
+		case "p", "left":
+			if current > 0 {
+				current, offset = current-1, 0
+			}
+		case "]":
+			for next := current + 1; next < len(sections); next++ {
+				if sections[next].Chapter != sections[current].Chapter {
+					current, offset = next, 0
+					break
+				}
+			}
+		case "[":
+			chapterStart := current
+			for chapterStart > 0 && sections[chapterStart-1].Chapter == sections[current].Chapter {
+				chapterStart--
+			}
+			if chapterStart == current && current > 0 {
+				chapterStart = current - 1
+				for chapterStart > 0 && sections[chapterStart-1].Chapter == sections[current-1].Chapter {
+					chapterStart--
+				}
+			}
+			current, offset = chapterStart, 0
+		case "j", "down":
+			offset++
+		case "k", "up":
+			if offset > 0 {
+				offset--
+			}
+		case "r":
+			sections[current].Result = []string{ansiYellow + "Running " + strings.Join(sections[current].Tests, ", ") + "..." + ansiReset}
+			tuiDraw(sections, current, offset, rows)
+			sections[current].Result = runSectionTests(sections[current])
+			// Scroll down to the results
+			offset = len(tuiBody(sections[current]))
+		}
+	}
+}
+
+// tuiSections renders each chapter file and splits it at its level one
+// and level two headings, attaching the tests found under each heading.
+func tuiSections(chapterFiles []string) ([]*tuiSection, error) {
+	sections := []*tuiSection{}
+	chapter := ""
+	for _, fileName := range chapterFiles {
+		lineLocations, nextChapter, err := locations(fileName, chapter)
+		if err != nil {
+			return nil, err
+		}
+		data, err := os.ReadFile(fileName)
+		if err != nil {
+			return nil, err
+		}
+		tests := map[string][]string{}
+		for i, line := range strings.Split(string(data), "\n") {
+			if strings.HasPrefix(line, "func Test_") {
+				section := lineLocations[i+1].Section
+				tests[section] = append(tests[section], funcName(line))
+			}
+		}
+
+		var section *tuiSection
+		for _, line := range strings.Split(file2md(fileName), "\n") {
+			if h, ok := parseHeading("// " + line); ok && h.Level <= 2 {
+				if h.Level == 1 {
+					chapter = h.Title
+				}
+				title := ""
+				if h.Level == 2 {
+					title = h.Title
+				}
+				section = &tuiSection{Chapter: chapter, Title: title, File: fileName, Tests: tests[title]}
+				sections = append(sections, section)
+				continue
+			}
+			if section == nil {
+				section = &tuiSection{Chapter: chapter, File: fileName, Tests: tests[""]}
+				sections = append(sections, section)
+			}
+			section.Lines = append(section.Lines, line)
+		}
+		chapter = nextChapter
+	}
+	return sections, nil
+}
+
+// tuiDraw redraws the screen and returns the scroll offset, adjusted to
+// stay within the section.
+func tuiDraw(sections []*tuiSection, current int, offset int, rows int) int {
+	section := sections[current]
+	title := section.Chapter
+	if section.Title != "" {
+		title = title + " › " + section.Title
+	}
+	body := tuiBody(section)
+	height := rows - 4
+	if height < 1 {
+		height = 1
+	}
+	if offset > len(body)-height {
+		offset = len(body) - height
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	end := offset + height
+	if end > len(body) {
+		end = len(body)
+	}
+
+	screen := ansiClear
+	screen = screen + fmt.Sprintf("%s%s%s %s(%d/%d)%s\n", ansiBold, title, ansiReset, ansiDim, current+1, len(sections), ansiReset)
+	screen = screen + ansiDim + strings.Repeat("─", 60) + ansiReset + "\n"
+	for _, line := range body[offset:end] {
+		screen = screen + line + "\n"
+	}
+	for i := end - offset; i < height; i++ {
+		screen = screen + "\n"
+	}
+	screen = screen + ansiDim + strings.Repeat("─", 60) + ansiReset + "\n"
+	screen = screen + ansiDim + "n/p: section  [/]: chapter  j/k: scroll  r: run tests  q: quit" + ansiReset
+	os.Stdout.WriteString(screen)
+	return offset
+}
+
+// tuiBody returns the lines of a section ready to be printed, with code
+// coloured and the latest test results at the bottom.
+func tuiBody(section *tuiSection) []string {
+	body := []string{}
+	insideCode := false
+	for _, line := range section.Lines {
+		switch {
+		case strings.HasPrefix(line, "```"):
+			insideCode = !insideCode
+		case insideCode:
+			body = append(body, "  "+colourGo(line))
+		case strings.HasPrefix(line, "#"):
+			body = append(body, ansiBold+line+ansiReset)
+		case strings.HasPrefix(line, "Source: "):
+			body = append(body, ansiDim+line+ansiReset)
+		default:
+			body = append(body, line)
+		}
+	}
+	for len(body) > 0 && strings.TrimSpace(body[len(body)-1]) == "" {
+		body = body[:len(body)-1]
+	}
+	if len(section.Result) > 0 {
+		body = append(body, "")
+		body = append(body, section.Result...)
+	}
+	return body
+}
+
+// colourGo highlights a line of Go code. Text the scanner does not
+// recognise, such as the ⇔ symbol, is kept as it is.
+func colourGo(line string) string {
+	src := []byte(line)
+	fset := token.NewFileSet()
+	file := fset.AddFile("", fset.Base(), len(src))
+	var s scanner.Scanner
+	s.Init(file, src, nil, scanner.ScanComments)
+	coloured := ""
+	last := 0
+	for {
+		pos, tok, lit := s.Scan()
+		if tok == token.EOF {
+			break
+		}
+		if tok == token.SEMICOLON && lit == "\n" {
+			continue
+		}
+		start := file.Offset(pos)
+		end := start + len(tok.String())
+		if lit != "" {
+			end = start + len(lit)
+		}
+		if start < last || end > len(src) {
+			continue
+		}
+		colour := ""
+		switch {
+		case tok == token.COMMENT:
+			colour = ansiDim
+		case tok == token.STRING || tok == token.CHAR:
+			colour = ansiGreen
+		case tok == token.INT || tok == token.FLOAT || tok == token.IMAG:
+			colour = ansiMagenta
+		case tok.IsKeyword():
+			colour = ansiBlue
+		case tok == token.IDENT && (lit == "true" || lit == "false" || lit == "nil"):
+			colour = ansiCyan
+		}
+		coloured = coloured + line[last:start]
+		if colour != "" {
+			coloured = coloured + colour + line[start:end] + ansiReset
+		} else {
+			coloured = coloured + line[start:end]
+		}
+		last = end
+	}
+	return coloured + line[last:]
+}
+
+// runSectionTests runs the tests under a section and summarises the
+// outcome of each one.
+func runSectionTests(section *tuiSection) []string {
+	if len(section.Tests) == 0 {
+		return []string{ansiDim + "No tests back this section" + ansiReset}
+	}
+	run := "^(" + strings.Join(section.Tests, "|") + ")$"
+	_, output, err := instrumentedTest(section.File, []string{"-v", "-run", run})
+	result := []string{}
+	for _, line := range strings.Split(output, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(trimmed, "--- PASS"):
+			result = append(result, ansiGreen+"✔ "+strings.TrimPrefix(trimmed, "--- PASS: ")+ansiReset)
+		case strings.HasPrefix(trimmed, "--- FAIL"):
+			result = append(result, ansiRed+"✘ "+strings.TrimPrefix(trimmed, "--- FAIL: ")+ansiReset)
+		case strings.Contains(trimmed, ".go:") && !strings.HasPrefix(trimmed, "==="):
+			result = append(result, ansiRed+"    "+trimmed+ansiReset)
+		}
+	}
+	if len(result) == 0 && err != nil {
+		result = append(result, ansiRed+err.Error()+ansiReset)
+	}
+	return result
+}
+
+// keyPressMode switches the terminal to deliver key presses without
+// waiting for Enter and without echoing them. It returns a function that
+// restores the previous settings, which an interrupt or termination
+// signal also does before exiting.
+func keyPressMode() (func(), error) {
+	saved, err := stty("-g")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := stty("-icanon", "-echo", "min", "1"); err != nil {
+		return nil, err
+	}
+	var once sync.Once
+	signals := make(chan os.Signal, 1)
+	restore := func() {
+		once.Do(func() {
+			signal.Stop(signals)
+			stty(strings.TrimSpace(saved))
+			close(signals)
+		})
+	}
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		if _, received := <-signals; received {
+			restore()
+			os.Stdout.WriteString(ansiClear)
+			os.Exit(1)
+		}
+	}()
+	return restore, nil
+}
+
+func stty(args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = os.Stdin
+	output, err := cmd.Output()
+	return string(output), err
+}
+
+// terminalSize returns the number of rows and columns of the terminal,
+// assuming 24x80 when they cannot be determined.
+func terminalSize() (int, int) {
+	output, err := stty("size")
+	if err != nil {
+		return 24, 80
+	}
+	fields := strings.Fields(output)
+	if len(fields) != 2 {
+		return 24, 80
+	}
+	rows, err1 := strconv.Atoi(fields[0])
+	columns, err2 := strconv.Atoi(fields[1])
+	if err1 != nil || err2 != nil || rows <= 0 || columns <= 0 {
+		return 24, 80
+	}
+	return rows, columns
+}
+
+// readKey reads a key press, naming the arrow keys.
+func readKey(keys *bufio.Reader) (string, error) {
+	b, err := keys.ReadByte()
+	if err != nil {
+		return "", err
+	}
+	if b != 0x1b {
+		return string(b), nil
+	}
+	if next, err := keys.ReadByte(); err != nil || next != '[' {
+		return "escape", err
+	}
+	arrow, err := keys.ReadByte()
+	if err != nil {
+		return "", err
+	}
+	switch arrow {
+	case 'A':
+		return "up", nil
+	case 'B':
+		return "down", nil
+	case 'C':
+		return "right", nil
+	case 'D':
+		return "left", nil
+	}
+	return "escape", nil
+}
@@ -0,0 +1,306 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// In values mode, each chapter package is copied to a temporary directory
+// where the testify import is replaced by a recording assert (see
+// valuesAssertSource) and its tests are run. The values of the operands of
+// every assert.Equal and assert.NotEqual call are then shown next to the
+// rendered assertion when at least one operand is not a literal.
+
+const testifyImport = `"github.com/stretchr/testify/assert"`
+
+const valuesFileName = "zz_go2md_values_test.go"
+
+const maxDistinctValues = 3
+
+const valuesAssertSource = `package %s
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+type go2mdAssertions struct{}
+
+var assert go2mdAssertions
+
+func go2mdEqual(expected, actual interface{}) bool {
+	if expected == nil || actual == nil {
+		return expected == actual
+	}
+	e, eIsBytes := expected.([]byte)
+	a, aIsBytes := actual.([]byte)
+	if eIsBytes && aIsBytes {
+		return bytes.Equal(e, a)
+	}
+	return reflect.DeepEqual(expected, actual)
+}
+
+func go2mdRecord(expected, actual interface{}, passed bool) {
+	_, file, line, _ := runtime.Caller(2)
+	f, err := os.OpenFile(os.Getenv("GO2MD_VALUES"), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	json.NewEncoder(f).Encode([]interface{}{
+		filepath.Base(file), line,
+		fmt.Sprintf("%%#v", expected), fmt.Sprintf("%%#v", actual), passed,
+	})
+}
+
+func (go2mdAssertions) Equal(t *testing.T, expected, actual interface{}, msgAndArgs ...interface{}) bool {
+	passed := go2mdEqual(expected, actual)
+	go2mdRecord(expected, actual, passed)
+	if !passed {
+		t.Errorf("Not equal: expected %%#v, actual %%#v", expected, actual)
+		return false
+	}
+	return true
+}
+
+func (go2mdAssertions) NotEqual(t *testing.T, expected, actual interface{}, msgAndArgs ...interface{}) bool {
+	passed := !go2mdEqual(expected, actual)
+	go2mdRecord(expected, actual, passed)
+	if !passed {
+		t.Errorf("Should not be: %%#v", actual)
+		return false
+	}
+	return true
+}
+`
+
+// assertValues holds the distinct values recorded for the expected and
+// actual operands of the assertion on a given line, and how many of the
+// times it was called it failed.
+type assertValues struct {
+	Expected []string
+	Actual   []string
+	Calls    int
+	Failures int
+}
+
+// Recorded values by directory and package name, then by file name and line.
+var valuesCache = map[string]map[string]map[int]*assertValues{}
+
+// recordedValues returns the values recorded for fileName's assertions,
+// running its package's tests the first time the package is seen.
+func recordedValues(fileName string) map[int]*assertValues {
+	dir, justFile := filepath.Split(fileName)
+	key := dir + ":" + packageName(fileName)
+	if _, found := valuesCache[key]; !found {
+		values, err := recordValues(fileName)
+		if err != nil {
+			os.Stderr.WriteString(fmt.Sprintf("Unable to record values for %s\n%v\n", fileName, err))
+		}
+		valuesCache[key] = values
+	}
+	return valuesCache[key][justFile]
+}
+
+// recordValues runs the instrumented tests of fileName's package.
+func recordValues(fileName string) (map[string]map[int]*assertValues, error) {
+	values, _, err := instrumentedTest(fileName, []string{})
+	return values, err
+}
+
+// instrumentedTest runs `go test` with testArgs on an instrumented copy
+// of fileName's package, returning the recorded values and the output.
+func instrumentedTest(fileName string, testArgs []string) (map[string]map[int]*assertValues, string, error) {
+	values := map[string]map[int]*assertValues{}
+	tmpDir, err := os.MkdirTemp("", "go2md-values")
+	if err != nil {
+		return values, "", err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	goFiles, err := instrumentedCopy(fileName, tmpDir, map[string]string{})
+	if err != nil {
+		return values, "", err
+	}
+
+	valuesFile := filepath.Join(tmpDir, "values.json")
+	args := append([]string{"test", "-count=1"}, testArgs...)
+	cmd := exec.Command("go", append(args, goFiles...)...)
+	cmd.Dir = tmpDir
+	cmd.Env = append(os.Environ(), "GO2MD_VALUES="+valuesFile)
+	output, err := cmd.CombinedOutput()
+	if _, statErr := os.Stat(valuesFile); statErr != nil {
+		// Nothing was recorded: either no assertion ran or the tests did not build
+		if err != nil {
+			return values, string(output), fmt.Errorf("%v\n%s", err, output)
+		}
+		return values, string(output), nil
+	}
+
+	file, err := os.Open(valuesFile)
+	if err != nil {
+		return values, "", err
+	}
+	defer file.Close()
+	input := bufio.NewScanner(file)
+	input.Buffer(nil, 1024*1024)
+	for input.Scan() {
+		var record []interface{}
+		if err := json.Unmarshal(input.Bytes(), &record); err != nil || len(record) != 5 {
+			continue
+		}
+		recordFile, _ := record[0].(string)
+		recordLine, _ := record[1].(float64)
+		expected, _ := record[2].(string)
+		actual, _ := record[3].(string)
+		passed, _ := record[4].(bool)
+		if values[recordFile] == nil {
+			values[recordFile] = map[int]*assertValues{}
+		}
+		line := int(recordLine)
+		if values[recordFile][line] == nil {
+			values[recordFile][line] = &assertValues{}
+		}
+		values[recordFile][line].Expected = appendDistinct(values[recordFile][line].Expected, expected)
+		values[recordFile][line].Actual = appendDistinct(values[recordFile][line].Actual, actual)
+		values[recordFile][line].Calls++
+		if !passed {
+			values[recordFile][line].Failures++
+		}
+	}
+	return values, string(output), nil
+}
+
+// instrumentedCopy copies fileName's package to tmpDir, replacing the
+// testify import with the recording assert, and returns the names of the
+// copied .go files. Files named in overrides are given the content there
+// instead of their own.
+func instrumentedCopy(fileName string, tmpDir string, overrides map[string]string) ([]string, error) {
+	dir, _ := filepath.Split(fileName)
+	entries, err := os.ReadDir(filepath.Clean(dir))
+	if err != nil {
+		return nil, err
+	}
+	pkg := packageName(fileName)
+	goFiles := []string{valuesFileName}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		if override, found := overrides[entry.Name()]; found {
+			data = []byte(override)
+		}
+		if strings.HasSuffix(entry.Name(), ".go") {
+			if packageName(filepath.Join(dir, entry.Name())) != pkg {
+				continue
+			}
+			goFiles = append(goFiles, entry.Name())
+			data = []byte(strings.Replace(string(data), testifyImport, "", 1))
+		}
+		if err := os.WriteFile(filepath.Join(tmpDir, entry.Name()), data, 0644); err != nil {
+			return nil, err
+		}
+	}
+	source := fmt.Sprintf(valuesAssertSource, pkg)
+	if err := os.WriteFile(filepath.Join(tmpDir, valuesFileName), []byte(source), 0644); err != nil {
+		return nil, err
+	}
+	return goFiles, nil
+}
+
+func appendDistinct(values []string, value string) []string {
+	for _, v := range values {
+		if v == value {
+			return values
+		}
+	}
+	return append(values, value)
+}
+
+// isLiteral reports whether expr is a literal whose value is evident
+// from its source: basic literals, negated basic literals, true, false,
+// nil, and composite literals made only of those.
+func isLiteral(expr ast.Expr) bool {
+	switch e := expr.(type) {
+	case *ast.BasicLit:
+		return true
+	case *ast.Ident:
+		return e.Name == "true" || e.Name == "false" || e.Name == "nil"
+	case *ast.UnaryExpr:
+		_, ok := e.X.(*ast.BasicLit)
+		return ok && (e.Op == token.SUB || e.Op == token.ADD)
+	case *ast.ParenExpr:
+		return isLiteral(e.X)
+	case *ast.KeyValueExpr:
+		return isLiteral(e.Value)
+	case *ast.CompositeLit:
+		for _, elt := range e.Elts {
+			if !isLiteral(elt) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// valuesComment describes the recorded values of the non-literal operands
+// of the assertion in line, or returns "" if there is nothing to add.
+func valuesComment(line string, values *assertValues) string {
+	if values == nil {
+		return ""
+	}
+	expr, err := parser.ParseExpr(strings.TrimSpace(line))
+	if err != nil {
+		return ""
+	}
+	call, ok := expr.(*ast.CallExpr)
+	if !ok || len(call.Args) < 3 {
+		return ""
+	}
+	described := []string{}
+	describe := func(operand ast.Expr, recorded []string) {
+		if isLiteral(operand) || len(recorded) == 0 {
+			return
+		}
+		shown := recorded
+		if len(shown) > maxDistinctValues {
+			shown = append(shown[:maxDistinctValues:maxDistinctValues], "…")
+		}
+		described = append(described, fmt.Sprintf("%s = %s", types.ExprString(operand), strings.Join(shown, " | ")))
+	}
+	describe(call.Args[1], values.Expected)
+	describe(call.Args[2], values.Actual)
+	return strings.Join(described, ", ")
+}
+
+// annotateValues appends comment to a rendered line, merging it with a
+// trailing comment if there is one already.
+func annotateValues(line string, comment string) string {
+	if comment == "" {
+		return line
+	}
+	if strings.Contains(line, " // ") {
+		return line + "; " + comment
+	}
+	return line + " // " + comment
+}
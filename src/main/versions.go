@@ -0,0 +1,465 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Each section is tagged with the minimum Go release its rendered code
+// needs, found from the standard library APIs it uses (looked up in the
+// API files of GOROOT, or else in goAPIVersions) and from the language
+// features it relies on. Sections needing more than Go 1.0 get a badge;
+// with -go, sections needing a newer release than the one given are
+// excluded or flagged.
+
+// goAPIVersions gives the release that introduced an API after Go 1.0,
+// named as path.Name, path.Type.Method, or just path for a whole package.
+// It stands in for the API files of GOROOT where those are missing.
+var goAPIVersions = map[string]string{
+	"bufio.NewScanner":        "1.1",
+	"bufio.ScanLines":         "1.1",
+	"bufio.ScanRunes":         "1.1",
+	"bufio.ScanWords":         "1.1",
+	"bufio.Scanner":           "1.1",
+	"bufio.Scanner.Buffer":    "1.6",
+	"bytes.Clone":             "1.20",
+	"bytes.Cut":               "1.18",
+	"bytes.ReplaceAll":        "1.12",
+	"cmp":                     "1.21",
+	"context":                 "1.7",
+	"embed":                   "1.16",
+	"errors.As":               "1.13",
+	"errors.ErrUnsupported":   "1.21",
+	"errors.Is":               "1.13",
+	"errors.Join":             "1.20",
+	"errors.Unwrap":           "1.13",
+	"fmt.Append":              "1.19",
+	"fmt.Appendf":             "1.19",
+	"fmt.Appendln":            "1.19",
+	"io.Discard":              "1.16",
+	"io.NopCloser":            "1.16",
+	"io.OffsetWriter":         "1.20",
+	"io.ReadAll":              "1.16",
+	"io.StringWriter":         "1.12",
+	"io/fs":                   "1.16",
+	"iter":                    "1.23",
+	"log/slog":                "1.21",
+	"maps":                    "1.21",
+	"math.MaxInt":             "1.17",
+	"math.MaxUint":            "1.17",
+	"math.MinInt":             "1.17",
+	"math/bits":               "1.9",
+	"math/rand/v2":            "1.22",
+	"os.CopyFS":               "1.23",
+	"os.CreateTemp":           "1.16",
+	"os.DirFS":                "1.16",
+	"os.MkdirTemp":            "1.16",
+	"os.ReadDir":              "1.16",
+	"os.ReadFile":             "1.16",
+	"os.UserCacheDir":         "1.11",
+	"os.UserConfigDir":        "1.13",
+	"os.UserHomeDir":          "1.12",
+	"os.WriteFile":            "1.16",
+	"os/exec.Cmd.Environ":     "1.19",
+	"os/exec.ErrDot":          "1.19",
+	"reflect.TypeFor":         "1.22",
+	"reflect.Value.IsZero":    "1.13",
+	"slices":                  "1.21",
+	"sort.Slice":              "1.8",
+	"sort.SliceStable":        "1.8",
+	"strconv.FormatComplex":   "1.15",
+	"strconv.ParseComplex":    "1.15",
+	"strconv.QuotedPrefix":    "1.17",
+	"strings.Builder":         "1.10",
+	"strings.Clone":           "1.18",
+	"strings.Cut":             "1.18",
+	"strings.CutPrefix":       "1.20",
+	"strings.CutSuffix":       "1.20",
+	"strings.FieldsSeq":       "1.24",
+	"strings.LastIndexByte":   "1.5",
+	"strings.Lines":           "1.24",
+	"strings.ReplaceAll":      "1.12",
+	"strings.SplitSeq":        "1.24",
+	"strings.ToValidUTF8":     "1.13",
+	"sync.Map":                "1.9",
+	"sync.Map.CompareAndSwap": "1.20",
+	"sync.Map.LoadAndDelete":  "1.15",
+	"sync.Map.Swap":           "1.20",
+	"sync.Mutex.TryLock":      "1.18",
+	"sync.OnceFunc":           "1.21",
+	"sync.OnceValue":          "1.21",
+	"sync.OnceValues":         "1.21",
+	"sync.RWMutex.TryLock":    "1.18",
+	"sync.WaitGroup.Go":       "1.25",
+	"sync/atomic.Bool":        "1.19",
+	"sync/atomic.Int32":       "1.19",
+	"sync/atomic.Int64":       "1.19",
+	"sync/atomic.Pointer":     "1.19",
+	"sync/atomic.Uint32":      "1.19",
+	"sync/atomic.Uint64":      "1.19",
+	"sync/atomic.Uintptr":     "1.19",
+	"sync/atomic.Value":       "1.4",
+	"testing.B.RunParallel":   "1.3",
+	"testing.F":               "1.18",
+	"testing.PB":              "1.3",
+	"testing.T.Cleanup":       "1.14",
+	"testing.T.Setenv":        "1.17",
+	"testing.T.TempDir":       "1.15",
+	"time.DateOnly":           "1.20",
+	"time.DateTime":           "1.20",
+	"time.Duration.Round":     "1.9",
+	"time.Duration.Truncate":  "1.9",
+	"time.Ticker.Reset":       "1.15",
+	"time.Time.Compare":       "1.20",
+	"time.Time.UnixMicro":     "1.17",
+	"time.Time.UnixMilli":     "1.17",
+	"time.TimeOnly":           "1.20",
+	"time.UnixMicro":          "1.17",
+	"time.UnixMilli":          "1.17",
+	"time.Until":              "1.8",
+	"unicode/utf8.AppendRune": "1.18",
+	"unique":                  "1.23",
+}
+
+// introducedVersions caches the result of apiVersions.
+var introducedVersions map[string]string
+
+// apiVersions returns the release that introduced each API after Go 1.0,
+// as read from the API files of GOROOT, or goAPIVersions if those cannot
+// be read.
+func apiVersions() map[string]string {
+	if introducedVersions == nil {
+		versions, err := readAPIVersions(filepath.Join(build.Default.GOROOT, "api"))
+		if err != nil {
+			versions = goAPIVersions
+		}
+		introducedVersions = versions
+	}
+	return introducedVersions
+}
+
+// readAPIVersions reads the API files in dir, go1.N.txt, which list what
+// each release added.
+func readAPIVersions(dir string) (map[string]string, error) {
+	apiFiles, err := filepath.Glob(filepath.Join(dir, "go1.*.txt"))
+	if err != nil {
+		return nil, err
+	}
+	if len(apiFiles) == 0 {
+		return nil, fmt.Errorf("no API files in %s", dir)
+	}
+	versions := map[string]string{}
+	introduce := func(name string, version string) {
+		if previous, found := versions[name]; !found || minor(version) < minor(previous) {
+			versions[name] = version
+		}
+	}
+	for _, apiFile := range apiFiles {
+		version := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(apiFile), "go"), ".txt")
+		data, err := os.ReadFile(apiFile)
+		if err != nil {
+			return nil, err
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			path, name, found := apiLine(line)
+			if !found {
+				continue
+			}
+			introduce(path, version)
+			if name != "" {
+				introduce(path+"."+name, version)
+			}
+		}
+	}
+	// What go1.txt lists is Go 1.0, even where a later file lists it
+	// again with a changed signature, as os.Stat returning fs.FileInfo
+	data, err := os.ReadFile(filepath.Join(dir, "go1.txt"))
+	if err != nil {
+		return nil, err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if path, name, found := apiLine(line); found {
+			delete(versions, path)
+			delete(versions, path+"."+name)
+		}
+	}
+	return versions, nil
+}
+
+// apiLinePattern matches the lines of an API file that add a package
+// member, such as "pkg bufio, method (*Scanner) Buffer([]uint8, int)",
+// capturing the path, the kind, the receiver of a method and the name.
+var apiLinePattern = regexp.MustCompile(`^pkg ([^ ,]+)(?: \([^)]*\))?, (func|method|type|const|var) (?:\(\*?(\w+)(?:\[[^\]]*\])?\) )?(\w+)`)
+
+// apiMemberPattern matches the rest of a line adding a field or an
+// interface method to a type, such as " struct, Timeout Duration".
+var apiMemberPattern = regexp.MustCompile(`^(?:\[.*\])? (?:struct|interface), (\w+)`)
+
+// apiLine returns the package path and the member name, as Name or
+// Type.Method, added by a line of an API file. Struct fields come back as
+// their type, and deprecation notices are not additions.
+func apiLine(line string) (string, string, bool) {
+	if strings.Contains(line, "//deprecated") {
+		return "", "", false
+	}
+	parts := apiLinePattern.FindStringSubmatch(line)
+	if parts == nil {
+		return "", "", false
+	}
+	path, receiver, name := parts[1], parts[3], parts[4]
+	if receiver != "" {
+		name = receiver + "." + name
+	} else if member := apiMemberPattern.FindStringSubmatch(line[len(parts[0]):]); parts[2] == "type" && member != nil {
+		name = name + "." + member[1]
+	}
+	return path, name, true
+}
+
+var goVersion = ""
+var newerSections = "exclude"
+
+// A requirement is the minimum release needed and what needs it.
+type requirement struct {
+	Version string
+	Reason  string
+}
+
+// parseGoVersion returns the minor number of a Go 1 release written as
+// 1.N, 1.N.P or go1.N, and whether version is one.
+func parseGoVersion(version string) (int, bool) {
+	parts := strings.Split(strings.TrimPrefix(version, "go"), ".")
+	if parts[0] != "1" || len(parts) > 3 {
+		return 0, false
+	}
+	numbers := []int{}
+	for _, part := range parts[1:] {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 || strings.HasPrefix(part, "+") {
+			return 0, false
+		}
+		numbers = append(numbers, n)
+	}
+	if len(numbers) == 0 {
+		return 0, true
+	}
+	return numbers[0], true
+}
+
+// minor returns the minor number of a release known to be valid.
+func minor(version string) int {
+	n, _ := parseGoVersion(version)
+	return n
+}
+
+func (r *requirement) raise(version string, reason string) {
+	if minor(version) > minor(r.Version) {
+		r.Version, r.Reason = version, reason
+	}
+}
+
+// sectionRequirements returns the requirement of each heading of fileName
+// by line, taking in the rendered code up to the next heading.
+func sectionRequirements(fileName string) (map[int]requirement, error) {
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	lines := strings.Split(string(data), "\n")
+	isCode, _ := renderedCode(lines)
+	fset, file, pkg, info, err := typeCheckPackage(fileName)
+	if err != nil {
+		return nil, err
+	}
+	lineRequirements := map[int]*requirement{}
+	raise := func(pos token.Pos, version string, reason string) {
+		// Uses are recorded across the files of the package
+		if fset.File(pos) != fset.File(file.Pos()) {
+			return
+		}
+		line := fset.Position(pos).Line
+		if !isCode[line] {
+			return
+		}
+		if lineRequirements[line] == nil {
+			lineRequirements[line] = &requirement{Version: "1.0"}
+		}
+		lineRequirements[line].raise(version, reason)
+	}
+
+	introduced := apiVersions()
+	qualified := qualifiedIdents(file, info)
+	for ident, obj := range info.Uses {
+		if obj == nil || obj.Pkg() == nil || obj.Pkg() == pkg {
+			if obj != nil && obj.Parent() == types.Universe {
+				switch obj.Name() {
+				case "any", "comparable":
+					raise(ident.Pos(), "1.18", obj.Name())
+				case "min", "max", "clear":
+					raise(ident.Pos(), "1.21", obj.Name()+" builtin")
+				}
+			}
+			continue
+		}
+		if path, found := qualified[ident]; found {
+			if version, found := introduced[path+"."+obj.Name()]; found {
+				raise(ident.Pos(), version, path+"."+obj.Name())
+			} else if version, found := introduced[path]; found {
+				raise(ident.Pos(), version, path)
+			}
+		} else if version, found := introduced[obj.Pkg().Path()+"."+memberName(obj)]; found && version != introduced[obj.Pkg().Path()] {
+			// Members of a package as old as the package itself may come
+			// from an older one by alias, as the methods of os.FileInfo
+			raise(ident.Pos(), version, obj.Pkg().Path()+"."+memberName(obj))
+		}
+	}
+
+	ast.Inspect(file, func(n ast.Node) bool {
+		switch node := n.(type) {
+		case *ast.FuncType:
+			if node.TypeParams != nil {
+				raise(node.Pos(), "1.18", "type parameters")
+			}
+		case *ast.TypeSpec:
+			if node.TypeParams != nil {
+				raise(node.Pos(), "1.18", "type parameters")
+			}
+			if node.Assign.IsValid() {
+				raise(node.Pos(), "1.9", "type alias")
+			}
+		case *ast.BasicLit:
+			if node.Kind == token.INT || node.Kind == token.FLOAT || node.Kind == token.IMAG {
+				literal := strings.ToLower(node.Value)
+				if strings.HasPrefix(literal, "0b") || strings.HasPrefix(literal, "0o") || strings.Contains(literal, "_") {
+					raise(node.Pos(), "1.13", "number literal "+node.Value)
+				}
+			}
+		case *ast.RangeStmt:
+			if t, found := info.Types[node.X]; found && t.Type != nil {
+				switch u := t.Type.Underlying().(type) {
+				case *types.Basic:
+					if u.Info()&types.IsInteger != 0 {
+						raise(node.Pos(), "1.22", "range over integer")
+					}
+				case *types.Signature:
+					raise(node.Pos(), "1.23", "range over function")
+				}
+			}
+		case *ast.CallExpr:
+			if array, ok := node.Fun.(*ast.ArrayType); ok && array.Len != nil && len(node.Args) == 1 {
+				if t, found := info.Types[node.Args[0]]; found && t.Type != nil {
+					if _, isSlice := t.Type.Underlying().(*types.Slice); isSlice {
+						raise(node.Pos(), "1.20", "slice to array conversion")
+					}
+				}
+			}
+		}
+		return true
+	})
+
+	found, err := headings(fileName)
+	if err != nil {
+		return nil, err
+	}
+	requirements := map[int]requirement{}
+	for i, h := range found {
+		end := len(lines)
+		if i < len(found)-1 {
+			end = found[i+1].Line - 1
+		}
+		r := requirement{Version: "1.0"}
+		for line := h.Line; line <= end; line++ {
+			if lineRequirement := lineRequirements[line]; lineRequirement != nil {
+				r.raise(lineRequirement.Version, lineRequirement.Reason)
+			}
+		}
+		requirements[h.Line] = r
+	}
+	return requirements, nil
+}
+
+// qualifiedIdents returns the package path of every identifier in file
+// qualified with a package name. Package members are looked up under the
+// package they are qualified with, as os.FileInfo has become an alias of
+// io/fs.FileInfo.
+func qualifiedIdents(file *ast.File, info *types.Info) map[*ast.Ident]string {
+	qualified := map[*ast.Ident]string{}
+	ast.Inspect(file, func(n ast.Node) bool {
+		if selector, ok := n.(*ast.SelectorExpr); ok {
+			if x, ok := selector.X.(*ast.Ident); ok {
+				if pkgName, ok := info.Uses[x].(*types.PkgName); ok {
+					qualified[selector.Sel] = pkgName.Imported().Path()
+				}
+			}
+		}
+		return true
+	})
+	return qualified
+}
+
+// memberName returns the name of obj within its package, as Type.Method
+// for methods.
+func memberName(obj types.Object) string {
+	if function, ok := obj.(*types.Func); ok {
+		if recv := function.Type().(*types.Signature).Recv(); recv != nil {
+			t := recv.Type()
+			if pointer, ok := t.(*types.Pointer); ok {
+				t = pointer.Elem()
+			}
+			if named, ok := t.(*types.Named); ok {
+				return named.Obj().Name() + "." + obj.Name()
+			}
+		}
+	}
+	return obj.Name()
+}
+
+// applyVersions adds a badge under every heading of doc whose section
+// needs more than Go 1.0. Sections needing a newer release than goVersion
+// are removed, along with their subsections, or flagged.
+func applyVersions(doc *document) error {
+	requirements, err := sectionRequirements(doc.File)
+	if err != nil {
+		return err
+	}
+	blocks := []block{}
+	excludedLevel := 0
+	for _, b := range doc.Blocks {
+		if b.Kind == headingBlock && excludedLevel > 0 && b.Level <= excludedLevel {
+			excludedLevel = 0
+		}
+		if excludedLevel > 0 {
+			continue
+		}
+		if b.Kind != headingBlock {
+			blocks = append(blocks, b)
+			continue
+		}
+		r := requirements[b.Line]
+		newer := goVersion != "" && minor(r.Version) > minor(goVersion)
+		if newer {
+			os.Stderr.WriteString(fmt.Sprintf("%s:%d: section %q requires Go %s (%s)\n", doc.File, b.Line, b.Lines[0], r.Version, r.Reason))
+			if newerSections == "exclude" {
+				excludedLevel = b.Level
+				continue
+			}
+		}
+		blocks = append(blocks, b)
+		if minor(r.Version) > 0 {
+			badge := block{Kind: badgeBlock, Lines: []string{"Go " + r.Version + "+"}, Label: r.Reason}
+			if newer {
+				badge.Lines[0] = badge.Lines[0] + " (newer than Go " + goVersion + ")"
+			}
+			blocks = append(blocks, badge)
+		}
+	}
+	doc.Blocks = blocks
+	return nil
+}
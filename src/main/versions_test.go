@@ -0,0 +1,121 @@
+package main
+
+import (
+	"go/build"
+	"path/filepath"
+	"testing"
+)
+
+func Test_ParseGoVersion(t *testing.T) {
+	for _, c := range []struct {
+		version string
+		minor   int
+		valid   bool
+	}{
+		{"1.18", 18, true},
+		{"1.21.5", 21, true},
+		{"go1.22", 22, true},
+		{"1", 0, true},
+		{"2.0", 0, false},
+		{"banana", 0, false},
+		{"1.x", 0, false},
+		{"1.21.5.1", 0, false},
+		{"", 0, false},
+	} {
+		minor, valid := parseGoVersion(c.version)
+		if minor != c.minor || valid != c.valid {
+			t.Errorf("parseGoVersion(%q) = %d, %v; want %d, %v", c.version, minor, valid, c.minor, c.valid)
+		}
+	}
+}
+
+func Test_APILine(t *testing.T) {
+	for _, c := range []struct {
+		line string
+		path string
+		name string
+	}{
+		{"pkg bufio, method (*Scanner) Buffer([]uint8, int)", "bufio", "Scanner.Buffer"},
+		{"pkg strings, func Cut(string, string) (string, string, bool)", "strings", "Cut"},
+		{"pkg slices, func Clone[$0 interface{ ~[]$1 }, $1 interface{}]($0) $0", "slices", "Clone"},
+		{"pkg sync/atomic, method (*Pointer[$0]) Load() *$0", "sync/atomic", "Pointer.Load"},
+		{"pkg iter, type Seq2[$0 interface{}, $1 interface{}] func(func($0, $1) bool)", "iter", "Seq2"},
+		{"pkg net/http, type Server struct, ErrorLog *log.Logger", "net/http", "Server.ErrorLog"},
+		{"pkg io, type StringWriter interface, WriteString(string) (int, error)", "io", "StringWriter.WriteString"},
+		{"pkg syscall (linux-386), const AF_ALG = 38", "syscall", "AF_ALG"},
+		{"pkg time, const DateOnly = \"2006-01-02\"", "time", "DateOnly"},
+	} {
+		path, name, found := apiLine(c.line)
+		if !found || path != c.path || name != c.name {
+			t.Errorf("apiLine(%q) = %q, %q, %v; want %q, %q", c.line, path, name, found, c.path, c.name)
+		}
+	}
+	if _, _, found := apiLine("pkg io/ioutil, func ReadAll //deprecated #42026"); found {
+		t.Errorf("apiLine took a deprecation notice for an addition")
+	}
+}
+
+func Test_APIVersions(t *testing.T) {
+	introduced, err := readAPIVersions(filepath.Join(build.Default.GOROOT, "api"))
+	if err != nil {
+		t.Skip(err)
+	}
+	for name, version := range map[string]string{
+		"bufio.Scanner":            "1.1",
+		"bufio.Scanner.Buffer":     "1.6",
+		"strings.Builder":          "1.10",
+		"strings.Cut":              "1.18",
+		"os.ReadFile":              "1.16",
+		"slices":                   "1.21",
+		"sync/atomic.Int64.Add":    "1.19",
+		"testing.T.Setenv":         "1.17",
+		"time.DateOnly":            "1.20",
+		"io/fs":                    "1.16",
+		"strings.LastIndexByte":    "1.5",
+		"net/http.Server.ErrorLog": "1.3",
+		"reflect.Value.IsZero":     "1.13",
+		"sync.WaitGroup.Go":        "1.25",
+		"unicode/utf8.AppendRune":  "1.18",
+		"os/exec.Cmd.Environ":      "1.19",
+		"errors.Is":                "1.13",
+		"math.MaxInt":              "1.17",
+		"time.Time.UnixMilli":      "1.17",
+		"sync.Map.LoadAndDelete":   "1.15",
+		"strconv.QuotedPrefix":     "1.17",
+		"context":                  "1.7",
+		"math/rand/v2":             "1.22",
+		"iter":                     "1.23",
+		"strings.SplitSeq":         "1.24",
+		"sync/atomic.Value":        "1.4",
+		"io.Discard":               "1.16",
+		"testing.F":                "1.18",
+		"time.Until":               "1.8",
+		"sort.Slice":               "1.8",
+	} {
+		if introduced[name] != version {
+			t.Errorf("%s introduced in %q; want %q", name, introduced[name], version)
+		}
+	}
+	// Go 1.0 APIs, even those listed again later with a new signature
+	for _, name := range []string{"os.Stat", "math.Pi", "time.Second", "io/ioutil.ReadAll", "os", "fmt.Println"} {
+		if version, found := introduced[name]; found {
+			t.Errorf("%s introduced in %q; want Go 1.0", name, version)
+		}
+	}
+}
+
+func Test_APIVersionsFallback(t *testing.T) {
+	if _, err := readAPIVersions(t.TempDir()); err == nil {
+		t.Errorf("readAPIVersions succeeded without API files")
+	}
+	introduced, err := readAPIVersions(filepath.Join(build.Default.GOROOT, "api"))
+	if err != nil {
+		t.Skip(err)
+	}
+	// The embedded table agrees with the API files
+	for name, version := range goAPIVersions {
+		if introduced[name] != version {
+			t.Errorf("goAPIVersions gives %s for %s; the API files %q", version, name, introduced[name])
+		}
+	}
+}
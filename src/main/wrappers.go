@@ -0,0 +1,29 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Tests are rendered in one of three ways: only their body, which reads
+// as prose (body); in full, wrapper included (full); or their body under
+// a caption naming the test, which doubles as an anchor so that the
+// reference can be browsed as an index of tests (caption). The -tests
+// flag sets the way for the whole build, and a //go2md:tests directive
+// overrides it up to the next heading.
+const testsDirective = "//go2md:tests"
+
+var testWrappers = "body"
+
+func validTestWrappers(mode string) bool {
+	return mode == "body" || mode == "full" || mode == "caption"
+}
+
+// parseTestsDirective parses the argument of a //go2md:tests line.
+func parseTestsDirective(line string) (string, error) {
+	mode := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), testsDirective))
+	if !validTestWrappers(mode) {
+		return "", fmt.Errorf("tests must be body, full or caption, not %q", mode)
+	}
+	return mode, nil
+}
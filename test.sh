@@ -1,9 +1,11 @@
 #!/bin/sh
-FILES=$(find . -name "*_test.go")
+FILES=$(find . -name "*_test.go" -not -path "./src/main/*")
 for file in $FILES
 do
   echo $file
   go test $file
 done
+echo ./src/main
+go test $(ls src/main/*.go)
         
         
@@ -6,13 +6,14 @@
 
 GIT_HUB_ROOT=https://github.com/egarbarino/go-by-assertion/tree/master/
 DST=/mnt/d/GDrive/garba/draft/general/go-by-assertion/go-by-assertion.md
-CONVERTER="go run src/main/go2md.go"
+CONVERTER="go run $(ls src/main/*.go | grep -v _test.go)"
 mkdir -p build
 while true
 do
   FILES=$(cat files | tr '\n' ' ')
-  cat header.md > build/go-by-assertion.md 
-  $CONVERTER -r $GIT_HUB_ROOT $FILES >> build/go-by-assertion.md 
+  $CONVERTER nocompile $FILES | grep -v ": ok: "
+  $CONVERTER refs $FILES
+  $CONVERTER -header header.md -r $GIT_HUB_ROOT -e $FILES > build/go-by-assertion.md
   pandoc -s -S --toc build/go-by-assertion.md -o build/go-by-assertion.html
   cp build/go-by-assertion.md $DST 
   inotifywait -r src files header.md